@@ -0,0 +1,9 @@
+package entity
+
+// Crop is the part of the source image a component uses, either in pixels or percentages such as "50%"
+type Crop struct {
+	X      interface{} `json:"x"`
+	Y      interface{} `json:"y"`
+	Width  interface{} `json:"w"`
+	Height interface{} `json:"h"`
+}
@@ -2,11 +2,50 @@ package entity
 
 // ImageComponent describes an image component in a request
 type ImageComponent struct {
+	// Set to group to render Children as a single component, with their positions relative to it,
+	// layout to also position the Children automatically, or slideshow to show each of the Slides in turn
+	Type     string   `json:"type"`
 	URL      string   `json:"url"`
 	Local    bool     `json:"local"`
 	Position Position `json:"pos"`
+	// Components with a higher z are drawn on top, the order in the request is kept for the same z
+	Z        int               `json:"z"`
+	Children []*ImageComponent `json:"children"`
+	// What an animated component does once it reaches the end: loop (the default), hold on the last frame or once
+	// to disappear
+	Playback string `json:"playback"`
+	// How many times an animated component plays before it stops, 0 keeps looping
+	Loop int `json:"loop"`
+	// When the component appears and disappears. Like every time in a request given as a bare number, such as a
+	// trim filter's start or a slide's hold, it's a number of frames of the request's default delay (its delay, or
+	// from its fps, otherwise 10cs). Strings of milliseconds like 500ms can be used instead
+	Start  interface{} `json:"start"`
+	End    interface{} `json:"end"`
+	Layout *Layout     `json:"layout"`
+	Slides []*Slide    `json:"slides"`
+	Crop   *Crop       `json:"crop"`
+	Sprite *Sprite     `json:"sprite"`
+	// How the image is resized into its position (fill, contain, cover, none or scale-down) and where it's aligned
+	Fit    string `json:"fit"`
+	Anchor string `json:"anchor"`
+	// The kernel used to resize the image, defaults to the request's
+	Resample string `json:"resample"`
+
+	Rotation float64 `json:"rot"`
+	// rad (the default) or deg
+	RotationUnit string `json:"rotUnit"`
+	// The point the component rotates around, defaults to the centre
+	Pivot     *Point     `json:"pivot"`
+	Transform *Transform `json:"transform"`
 
-	Rotation   float64   `json:"rot"`
 	Filters    []*Filter `json:"filter"`
 	Background string    `json:"background"`
+	// How the component is mixed with what's under it, e.g. multiply or screen. Empty is normal
+	Blend string `json:"blend"`
+	// From 0 to 1, defaults to fully opaque
+	Opacity *float64 `json:"opacity"`
+	Mask    *Mask    `json:"mask"`
+
+	// Skips rotating the image according to its EXIF orientation
+	IgnoreOrientation bool `json:"ignoreOrientation"`
 }
@@ -3,11 +3,40 @@ package entity
 // ImageRequest is a request to render an image
 type ImageRequest struct {
 	ImageComponents []*ImageComponent `json:"components"`
-	Metadata        *Metadata         `json:"metadata"`
-	Width           int               `json:"width"`
-	Height          int               `json:"height"`
-	Debug           bool              `json:"debug"`
-	Version         int               `json:"version"`
-	Compression     bool              `json:"compression"`
-	MaxWidth        int               `json:"maxWidth"`
+	// Filters applied to the whole canvas once the components have been drawn
+	Filters     []*Filter `json:"filters"`
+	Metadata    *Metadata `json:"metadata"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Debug       bool      `json:"debug"`
+	Version     int       `json:"version"`
+	Compression bool      `json:"compression"`
+	MaxWidth    int       `json:"maxWidth"`
+	// The kernel used to resize images: nearest, approx-bilinear, bilinear (the default), catmull-rom, lanczos,
+	// or auto (also pixel) to use nearest for whole number upscales
+	Resample string `json:"resample"`
+	// The most frames the output can have, defaults to 200
+	MaxFrames int `json:"maxFrames"`
+	// How much each channel of consecutive frames can differ by for them to be merged into one, -1 never merges
+	FrameTolerance int `json:"frameTolerance"`
+	// How many times the animation plays, 0 loops forever
+	Loops int `json:"loops"`
+	// The delay of frames without one in centiseconds (defaults to 10), or the frame rate to get it from instead
+	Delay int     `json:"delay"`
+	FPS   float64 `json:"fps"`
+	// Replaces the delay of each output frame in order, 0 keeps the frame's own delay
+	Delays []int `json:"delays"`
+	// What happens to each frame before the next is drawn: none, background or previous. Either one for every frame
+	// or a list with one for each frame. Defaults to picking none or background based on the components
+	Disposal interface{} `json:"disposal"`
+	// Grows the canvas so that rotated or out of bounds components aren't clipped
+	Expand bool `json:"expand"`
+	// Sizes the canvas to fit every component, with padding around them
+	AutoSize bool    `json:"autoSize"`
+	Padding  float64 `json:"padding"`
+	// A hex colour drawn under every component, or a list of them for a gradient
+	Background string   `json:"background"`
+	Gradient   []string `json:"gradient"`
+	// The direction of the gradient in degrees like CSS, 0 goes to the top and 90 to the right. Defaults to 180, to the bottom
+	GradientAngle *float64 `json:"gradientAngle"`
 }
@@ -0,0 +1,20 @@
+package entity
+
+// Layout arranges the children of a layout component so they don't need their own positions
+type Layout struct {
+	// row (the default), column or grid
+	Direction string `json:"direction"`
+	// The number of columns in a grid, defaults to as close to square as possible
+	Columns int     `json:"columns"`
+	Gap     float64 `json:"gap"`
+	Padding float64 `json:"padding"`
+	// Where children sit across their line (or vertically in a grid cell): start, center, end or stretch
+	Align string `json:"align"`
+	// Where children sit along their line (or horizontally in a grid cell): start, center, end or space-between
+	Justify string `json:"justify"`
+	// Moves children onto a new line when they don't fit, rows and columns only
+	Wrap bool `json:"wrap"`
+	// The size given to children without one, which they need unless the layout has room to share out
+	ItemWidth  float64 `json:"itemWidth"`
+	ItemHeight float64 `json:"itemHeight"`
+}
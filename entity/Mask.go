@@ -0,0 +1,12 @@
+package entity
+
+// Mask describes which parts of a component are visible, using either the alpha of an image or a shape
+type Mask struct {
+	URL   string `json:"url"`
+	Local bool   `json:"local"`
+
+	// circle, roundrect or polygon, sized to the component
+	Shape  string      `json:"shape"`
+	Radius float64     `json:"radius"`
+	Points [][]float64 `json:"points"`
+}
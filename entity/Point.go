@@ -0,0 +1,7 @@
+package entity
+
+// Point is a position relative to a component, either in pixels or percentages such as "50%"
+type Point struct {
+	X interface{} `json:"x"`
+	Y interface{} `json:"y"`
+}
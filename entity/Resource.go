@@ -0,0 +1,12 @@
+package entity
+
+// Resource describes an image or font that can be used from the res directory
+type Resource struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Format   string `json:"format,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+// Slide is one of the images shown by a slideshow component
+type Slide struct {
+	URL   string `json:"url"`
+	Local bool   `json:"local"`
+	// How long the slide is shown for, as frames of the request's default delay or milliseconds like 2000ms. Defaults to 2 seconds for
+	// still images and one play of an animation
+	Hold interface{} `json:"hold"`
+	// How the slide replaces the one before: crossfade, dissolve, zoom, none, or slide or wipe followed by the
+	// direction they move such as slide-up. Defaults to crossfade
+	Transition string `json:"transition"`
+	// How long the transition takes, defaults to 500ms
+	TransitionDuration interface{} `json:"transitionDuration"`
+	// How the image is resized to the slideshow, defaults to contain
+	Fit string `json:"fit"`
+}
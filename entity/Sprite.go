@@ -0,0 +1,13 @@
+package entity
+
+// Sprite slices the source image into a grid of tiles which are used as animation frames
+type Sprite struct {
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+	// The number of tiles in the sheet if the last row isn't full
+	Count int `json:"count"`
+	// The delay of each frame in centiseconds, defaults to the request's delay
+	Delay int `json:"delay"`
+	// Which tiles to use and in what order, a single tile gives a still image
+	Frames []int `json:"frames"`
+}
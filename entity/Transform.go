@@ -0,0 +1,10 @@
+package entity
+
+// Transform maps a component onto the canvas with either an affine matrix or four corner points,
+// replacing its position and rotation
+type Transform struct {
+	// An affine matrix in the same order as CSS and SVG: [a, b, c, d, e, f] maps x,y to ax+cy+e, bx+dy+f
+	Matrix []float64 `json:"matrix"`
+	// Where the top left, top right, bottom right and bottom left corners go on the canvas, for perspective
+	Corners [][]float64 `json:"corners"`
+}
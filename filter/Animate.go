@@ -1,20 +1,69 @@
 package filter
 
 import (
+	"fmt"
 	"github.com/fogleman/gg"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
 	"image"
+	"math"
+	"sort"
 )
 
 type Animate struct{}
 
-func (a Animate) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
-	imageDeficit := float64(len(filter.Arguments["frames"].([]interface{})) - len(*images))
-	i := 0
+// keyframe is a set of values the component has at a point in the animation
+type keyframe struct {
+	// In centiseconds from the start of the animation
+	time   int
+	values map[string]interface{}
+	// The easing from this keyframe to the next one
+	easing string
+}
 
+func (a Animate) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
 	delay := int(helper.GetFloatDefault(filter.Arguments["delay"], 5))
 
+	if filter.Arguments["keyframes"] == nil {
+		frames, _ := filter.Arguments["frames"].([]interface{})
+		padAnimation(images, delays, len(frames), delay)
+		return
+	}
+
+	// The keyframes are tweened into the values for each frame at the time it's shown, which needs the frame delays
+	frameLength := helper.GetRequestDelay(request)
+	keyframes := getKeyframes(filter.Arguments, frameLength)
+	end := 0
+	if len(keyframes) > 0 {
+		end = keyframes[len(keyframes)-1].time
+	}
+
+	// Padding gives a still image the animation's delay, so it needs to be known before timing the first frame
+	if len(*delays) == 0 {
+		*delays = []int{delay}
+	}
+
+	frames := make([]interface{}, 0, len(*images))
+	time := 0
+	// There needs to be a frame shown at or before the last keyframe
+	for frameNum := 0; frameNum < len(*images) || (time <= end && frameNum < maxGeneratedFrames); frameNum++ {
+		padAnimation(images, delays, frameNum+1, delay)
+		frames = append(frames, tween(keyframes, time))
+		if frameNum < len(*delays) && (*delays)[frameNum] > 0 {
+			time += (*delays)[frameNum]
+		} else {
+			time += frameLength
+		}
+	}
+	filter.Arguments["frames"] = frames
+	delete(filter.Arguments, "keyframes")
+}
+
+// padAnimation repeats the component's frames until there are frameCount of them
+func padAnimation(images *[]*image.Image, delays *[]int, frameCount int, delay int) {
+	imageDeficit := float64(frameCount - len(*images))
+	i := 0
+
 	for imageDeficit > 0 {
 		*images = append(*images, (*images)[i%len(*images)])
 		if len(*delays) == 0 {
@@ -26,10 +75,13 @@ func (a Animate) AfterStacking(filter *entity.Filter, request *entity.ImageReque
 	}
 }
 
+// BeforeRender sets the component to the values of the frame. Keyframes have already been made into frames by AfterStacking
 func (a Animate) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNum int, component *entity.ImageComponent) *gg.Context {
-	animFrames := args["frames"].([]interface{})
-
-	animFrame := animFrames[frameNum%len(animFrames)].(map[string]interface{})
+	animFrames, _ := args["frames"].([]interface{})
+	if len(animFrames) == 0 {
+		return ctx
+	}
+	animFrame, _ := animFrames[frameNum%len(animFrames)].(map[string]interface{})
 
 	if animFrame["x"] != nil {
 		component.Position.X = animFrame["x"]
@@ -51,5 +103,93 @@ func (a Animate) BeforeRender(ctx *gg.Context, args map[string]interface{}, fram
 		component.Rotation = animFrame["rotation"].(float64)
 	}
 
+	if opacity, ok := animFrame["opacity"].(float64); ok {
+		component.Opacity = &opacity
+	}
+
 	return ctx
 }
+
+// getKeyframes reads the keyframes in time order. Times are in frames of the request's delay, or milliseconds
+func getKeyframes(args map[string]interface{}, delay int) []*keyframe {
+	defaultEasing := helper.GetStringDefault(args["easing"], "linear")
+	rawKeyframes, _ := args["keyframes"].([]interface{})
+	keyframes := make([]*keyframe, 0, len(rawKeyframes))
+	for i, rawKeyframe := range rawKeyframes {
+		values, ok := rawKeyframe.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keyframes = append(keyframes, &keyframe{
+			// Without a time, keyframes are a frame apart
+			time:   helper.GetCentiseconds(values["time"], delay, i*delay),
+			values: values,
+			easing: helper.GetStringDefault(values["easing"], defaultEasing),
+		})
+	}
+	sort.SliceStable(keyframes, func(i, j int) bool {
+		return keyframes[i].time < keyframes[j].time
+	})
+	return keyframes
+}
+
+// tween gets the value of each property at a time, eased between the keyframes either side that set it
+func tween(keyframes []*keyframe, time int) map[string]interface{} {
+	output := make(map[string]interface{})
+	for _, property := range []string{"x", "y", "w", "h", "rotation", "opacity", "background"} {
+		var previous, next *keyframe
+		for _, frame := range keyframes {
+			if frame.values[property] == nil {
+				continue
+			}
+			if frame.time <= time {
+				previous = frame
+			} else {
+				next = frame
+				break
+			}
+		}
+
+		if previous == nil {
+			// Before the first keyframe the component stays at it
+			if next != nil {
+				output[property] = next.values[property]
+			}
+			continue
+		}
+		if next == nil {
+			output[property] = previous.values[property]
+			continue
+		}
+
+		progress := helper.Ease(previous.easing, float64(time-previous.time)/float64(next.time-previous.time))
+		output[property] = interpolate(previous.values[property], next.values[property], progress)
+	}
+	return output
+}
+
+// interpolate blends numbers or hex colours, anything else (such as percentages) switches at the end
+func interpolate(from interface{}, to interface{}, progress float64) interface{} {
+	fromFloat, fromOk := from.(float64)
+	toFloat, toOk := to.(float64)
+	if fromOk && toOk {
+		return fromFloat + (toFloat-fromFloat)*progress
+	}
+
+	fromString, fromOk := from.(string)
+	toString, toOk := to.(string)
+	if fromOk && toOk && len(fromString) > 0 && fromString[0] == '#' && len(toString) > 0 && toString[0] == '#' {
+		fromColour := helper.ParseHexColour(fromString)
+		toColour := helper.ParseHexColour(toString)
+		mix := func(a uint8, b uint8) uint8 {
+			// Some easings overshoot, which would wrap around
+			return uint8(math.Max(0, math.Min(255, float64(a)+(float64(b)-float64(a))*progress+0.5)))
+		}
+		return fmt.Sprintf("#%02x%02x%02x%02x", mix(fromColour.R, toColour.R), mix(fromColour.G, toColour.G), mix(fromColour.B, toColour.B), mix(fromColour.A, toColour.A))
+	}
+
+	if progress >= 1 {
+		return to
+	}
+	return from
+}
@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"github.com/fogleman/gg"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"testing"
+)
+
+func TestAnimateKeyframes(t *testing.T) {
+	component := sizedComponent(0, 0, 10)
+	animate := &entity.Filter{Name: "animate", Arguments: map[string]interface{}{
+		"delay": float64(10),
+		"keyframes": []interface{}{
+			map[string]interface{}{"time": float64(0), "x": float64(0)},
+			map[string]interface{}{"time": "400ms", "x": float64(40), "opacity": float64(1)},
+			map[string]interface{}{"time": float64(6), "opacity": float64(0)},
+		},
+	}}
+	images := solidFrames(1, 10, 10, red)
+	delays := []int{}
+	Animate{}.AfterStacking(animate, &entity.ImageRequest{}, component, &images, &delays)
+
+	assert.Len(t, images, 7)
+	assert.Equal(t, repeatDelay(10, 7), delays)
+	for frameNum, x := range []float64{0, 10, 20, 30, 40} {
+		Animate{}.BeforeRender(gg.NewContext(10, 10), animate.Arguments, frameNum, component)
+		assert.Equal(t, x, component.Position.X, "frame %d", frameNum)
+	}
+	// Fading out between 40 and 60
+	Animate{}.BeforeRender(gg.NewContext(10, 10), animate.Arguments, 5, component)
+	assert.Equal(t, 0.5, *component.Opacity)
+	Animate{}.BeforeRender(gg.NewContext(10, 10), animate.Arguments, 6, component)
+	assert.Equal(t, float64(0), *component.Opacity)
+}
+
+func TestAnimateKeyframeTiming(t *testing.T) {
+	component := sizedComponent(0, 0, 10)
+	animate := &entity.Filter{Name: "animate", Arguments: map[string]interface{}{
+		"delay": float64(10),
+		"keyframes": []interface{}{
+			map[string]interface{}{"time": float64(0), "x": float64(0)},
+			// 4 frames of the request's delay is 20cs, which is 2 frames of the animation's delay
+			map[string]interface{}{"time": float64(4), "x": float64(40)},
+		},
+	}}
+	images := solidFrames(1, 10, 10, red)
+	delays := []int{}
+	Animate{}.AfterStacking(animate, &entity.ImageRequest{Delay: 5}, component, &images, &delays)
+
+	assert.Equal(t, []int{10, 10, 10}, delays)
+	for frameNum, x := range []float64{0, 20, 40} {
+		Animate{}.BeforeRender(gg.NewContext(10, 10), animate.Arguments, frameNum, component)
+		assert.Equal(t, x, component.Position.X, "frame %d", frameNum)
+	}
+}
@@ -13,8 +13,24 @@ var Filters = map[string]interface{}{
 	"greyscale": Greyscale{},
 	"hyper":     Hyper{},
 	"animate":   Animate{},
+	"shake":     Shake{},
+	"spin":      Spin{},
+	"zoom":      Zoom{},
+	"bounce":    Bounce{},
+	"slide":     Slide{},
+	"wobble":    Wobble{},
+	"squash":    Squash{},
+	"reverse":   Reverse{},
+	"boomerang": Boomerang{},
+	"trim":      Trim{},
+	"speed":     Speed{},
+	"fps":       FPS{},
 }
 
+// LoadImage gets every frame of an image the same way components are loaded, for filters that draw other images.
+// It's set by the stage package, which can't be imported from here
+var LoadImage func(component *entity.ImageComponent) ([]*image.Image, []int, error)
+
 type BeforeRender interface {
 	BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNum int, component *entity.ImageComponent) *gg.Context
 }
@@ -26,3 +42,8 @@ type BeforeStacking interface {
 type AfterStacking interface {
 	AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int)
 }
+
+// Validator is for filters that can check their arguments before anything is rendered
+type Validator interface {
+	Validate(args map[string]interface{}) error
+}
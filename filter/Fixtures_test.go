@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	red         = color.RGBA{R: 255, A: 255}
+	blue        = color.RGBA{B: 255, A: 255}
+	transparent = color.RGBA{}
+)
+
+// solidFrames makes count frames of a single colour
+func solidFrames(count int, width int, height int, colour color.RGBA) []*image.Image {
+	frames := make([]*image.Image, count)
+	for i := range frames {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, frame.Rect, image.NewUniform(colour), image.Point{}, draw.Src)
+		rgba := image.Image(frame)
+		frames[i] = &rgba
+	}
+	return frames
+}
+
+// sizedComponent is a component at a position with a size, as it would be by the time its filters run
+func sizedComponent(x float64, y float64, size float64) *entity.ImageComponent {
+	return &entity.ImageComponent{Position: entity.Position{X: x, Y: y, Width: size, Height: size}}
+}
+
+// applyFilters runs the AfterStacking filters over the frames in order
+func applyFilters(request *entity.ImageRequest, component *entity.ImageComponent, filters []*entity.Filter, images *[]*image.Image, delays *[]int) {
+	for _, filterData := range filters {
+		Filters[filterData.Name].(AfterStacking).AfterStacking(filterData, request, component, images, delays)
+	}
+}
+
+func repeatDelay(delay int, count int) []int {
+	delays := make([]int, count)
+	for i := range delays {
+		delays[i] = delay
+	}
+	return delays
+}
+
+func assertPixel(t *testing.T, frame *image.Image, x int, y int, expected color.RGBA) {
+	assert.Equal(t, expected, color.RGBAModel.Convert((*frame).At(x, y)), "pixel at %d,%d", x, y)
+}
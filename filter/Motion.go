@@ -0,0 +1,214 @@
+package filter
+
+import (
+	"github.com/fogleman/gg"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// motion is how far a generated frame is moved from where it started, scaling and rotating about the centre
+type motion struct {
+	x        float64
+	y        float64
+	rotation float64
+	scale    float64
+}
+
+// The most frames a filter can generate, the same as the default most frames of the output
+const maxGeneratedFrames = 200
+
+// motionSettings are the arguments shared by every motion generator
+type motionSettings struct {
+	// How many frames there are and the progress through them, from 0 up to 1
+	frames   int
+	progress float64
+	// How many times the motion repeats over the animation
+	speed     float64
+	amplitude float64
+	seed      int64
+	width     float64
+	height    float64
+}
+
+// phase gets the angle through the motion's cycle, for motions that repeat
+func (s *motionSettings) phase() float64 {
+	return s.progress * s.speed * 2 * math.Pi
+}
+
+type Shake struct{}
+type Spin struct{}
+type Zoom struct{}
+type Bounce struct{}
+type Slide struct{}
+type Wobble struct{}
+
+// Shake jitters the image by up to amplitude pixels (default 5) in random directions, seed picks the randomness
+// and the same jitters repeat speed times
+func (m Shake) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	generateMotion(filter, request, component, images, delays, 5, func(settings *motionSettings) motion {
+		step := int(math.Mod(settings.progress*settings.speed, 1) * float64(settings.frames))
+		random := rand.New(rand.NewSource(settings.seed + int64(step)))
+		return motion{
+			x:     (random.Float64()*2 - 1) * settings.amplitude,
+			y:     (random.Float64()*2 - 1) * settings.amplitude,
+			scale: 1,
+		}
+	})
+}
+
+// Spin turns the image a full circle speed times, a negative speed spins anticlockwise
+func (m Spin) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	generateMotion(filter, request, component, images, delays, 0, func(settings *motionSettings) motion {
+		return motion{rotation: settings.phase(), scale: 1}
+	})
+}
+
+// Zoom pulses the size of the image by amplitude times its size (default 0.1)
+func (m Zoom) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	generateMotion(filter, request, component, images, delays, 0.1, func(settings *motionSettings) motion {
+		return motion{scale: 1 + settings.amplitude*math.Sin(settings.phase())}
+	})
+}
+
+// Bounce hops the image up by amplitude pixels (default 10% of its height)
+func (m Bounce) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	generateMotion(filter, request, component, images, delays, -1, func(settings *motionSettings) motion {
+		amplitude := settings.amplitude
+		if amplitude < 0 {
+			amplitude = settings.height / 10
+		}
+		return motion{y: -amplitude * math.Abs(math.Sin(settings.phase()/2)), scale: 1}
+	})
+}
+
+// Slide moves the image in from off the edge given by direction (left, right, top or bottom), stopping on the last frame
+func (m Slide) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	direction := helper.GetStringDefault(filter.Arguments["direction"], "left")
+	easing := helper.GetStringDefault(filter.Arguments["easing"], "ease-out")
+	generateMotion(filter, request, component, images, delays, 0, func(settings *motionSettings) motion {
+		progress := 1.0
+		if settings.frames > 1 {
+			// Stretch progress so the last frame is where the image ends up
+			progress = settings.progress * float64(settings.frames) / float64(settings.frames-1)
+		}
+		remaining := 1 - helper.Ease(easing, progress)
+		switch direction {
+		case "right":
+			return motion{x: remaining * settings.width, scale: 1}
+		case "top":
+			return motion{y: -remaining * settings.height, scale: 1}
+		case "bottom":
+			return motion{y: remaining * settings.height, scale: 1}
+		}
+		return motion{x: -remaining * settings.width, scale: 1}
+	})
+}
+
+// Wobble rocks the image back and forth by amplitude degrees (default 10)
+func (m Wobble) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	generateMotion(filter, request, component, images, delays, 10, func(settings *motionSettings) motion {
+		return motion{rotation: settings.amplitude * math.Pi / 180 * math.Sin(settings.phase()), scale: 1}
+	})
+}
+
+// generateMotion redraws the component's frames moved by the motion, making as many frames as the frames argument
+// (default 10, or the number of frames the image has if that's more), each shown for delay centiseconds (default 5).
+// The frames are padded so the image can move outside of its box, with the component grown to match
+func generateMotion(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int, defaultAmplitude float64, move func(settings *motionSettings) motion) {
+	frameCount := frameCountArgument(filter.Arguments["frames"], 10)
+	if frameCount < len(*images) {
+		frameCount = len(*images)
+	}
+	delay := int(helper.GetFloatDefault(filter.Arguments["delay"], 5))
+
+	bounds := (*(*images)[0]).Bounds()
+	settings := &motionSettings{
+		frames:    frameCount,
+		speed:     helper.GetFloatDefault(filter.Arguments["speed"], 1),
+		amplitude: helper.GetFloatDefault(filter.Arguments["amplitude"], defaultAmplitude),
+		seed:      int64(helper.GetFloatDefault(filter.Arguments["seed"], 1)),
+		width:     float64(bounds.Dx()),
+		height:    float64(bounds.Dy()),
+	}
+
+	motions := make([]motion, frameCount)
+	padX, padY := 0.0, 0.0
+	for i := range motions {
+		settings.progress = float64(i) / float64(frameCount)
+		motions[i] = move(settings)
+		motionPadX, motionPadY := motionPadding(motions[i], settings.width, settings.height)
+		padX = math.Max(padX, motionPadX)
+		padY = math.Max(padY, motionPadY)
+	}
+
+	outputImages := make([]*image.Image, frameCount)
+	outputDelay := make([]int, frameCount)
+	for i, frameMotion := range motions {
+		ctx := gg.NewContext(bounds.Dx()+int(padX)*2, bounds.Dy()+int(padY)*2)
+		ctx.Translate(padX+settings.width/2+frameMotion.x, padY+settings.height/2+frameMotion.y)
+		ctx.Rotate(frameMotion.rotation)
+		ctx.Scale(frameMotion.scale, frameMotion.scale)
+		ctx.DrawImageAnchored(*(*images)[i%len(*images)], 0, 0, 0.5, 0.5)
+		frame := ctx.Image()
+		outputImages[i] = &frame
+
+		// Animated images keep their own timing
+		outputDelay[i] = delay
+		if len(*delays) > 0 {
+			outputDelay[i] = (*delays)[i%len(*delays)]
+		}
+	}
+
+	padComponent(request, component, padX, padY, settings.width, settings.height)
+	*images = outputImages
+	*delays = outputDelay
+}
+
+// motionPadding gets how far past each side of its box the image goes once moved, in whole pixels
+func motionPadding(frameMotion motion, width float64, height float64) (float64, float64) {
+	sin, cos := math.Sincos(frameMotion.rotation)
+	padX, padY := 0.0, 0.0
+	for _, corner := range [][2]float64{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		cornerX := corner[0] * width / 2 * frameMotion.scale
+		cornerY := corner[1] * height / 2 * frameMotion.scale
+		padX = math.Max(padX, math.Abs(frameMotion.x+cornerX*cos-cornerY*sin)-width/2)
+		padY = math.Max(padY, math.Abs(frameMotion.y+cornerX*sin+cornerY*cos)-height/2)
+	}
+	return math.Ceil(padX), math.Ceil(padY)
+}
+
+// padComponent grows the component by the padding added to its frames and moves it so the image stays in place,
+// unless the canvas is sized from the component, in which case the canvas grows instead
+func padComponent(request *entity.ImageRequest, component *entity.ImageComponent, padX float64, padY float64, width float64, height float64) {
+	sizesCanvas := len(request.ImageComponents) > 0 && request.ImageComponents[0] == component
+	padPosition := func(position *interface{}, size *interface{}, canvasSize int, pad float64, frameSize float64) {
+		scale := 1.0
+		if relative, ok := (*size).(string); ok {
+			*size = helper.GetRelativeDimension(canvasSize, relative)
+		}
+		if componentSize, ok := (*size).(float64); ok && componentSize != 0 {
+			scale = componentSize / frameSize
+			*size = componentSize + pad*2*scale
+		}
+		if value, ok := (*position).(float64); ok && !(sizesCanvas && canvasSize == 0) {
+			*position = value - pad*scale
+		}
+	}
+	padPosition(&component.Position.X, &component.Position.Width, request.Width, padX, width)
+	padPosition(&component.Position.Y, &component.Position.Height, request.Height, padY, height)
+}
+
+// frameCountArgument gets how many frames a filter should generate, between 1 and maxGeneratedFrames
+func frameCountArgument(value interface{}, defaultValue int) int {
+	frameCount := int(helper.GetFloatDefault(value, float64(defaultValue)))
+	if frameCount < 1 {
+		return 1
+	}
+	if frameCount > maxGeneratedFrames {
+		return maxGeneratedFrames
+	}
+	return frameCount
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"testing"
+)
+
+func TestMotionGenerators(t *testing.T) {
+	for _, name := range []string{"shake", "spin", "zoom", "bounce", "slide", "wobble"} {
+		images := solidFrames(1, 20, 20, red)
+		delays := []int{}
+		filters := []*entity.Filter{{Name: name, Arguments: map[string]interface{}{"frames": float64(8), "delay": float64(4)}}}
+		applyFilters(&entity.ImageRequest{}, sizedComponent(0, 0, 20), filters, &images, &delays)
+		assert.Len(t, images, 8, name)
+		assert.Equal(t, repeatDelay(4, 8), delays, name)
+	}
+}
+
+func TestMotionLeavesItsBox(t *testing.T) {
+	component := sizedComponent(10, 20, 20)
+	images := solidFrames(1, 20, 20, red)
+	delays := []int{}
+	bounce := &entity.Filter{Name: "bounce", Arguments: map[string]interface{}{"frames": float64(4), "amplitude": float64(10)}}
+	Bounce{}.AfterStacking(bounce, &entity.ImageRequest{Width: 40, Height: 40}, component, &images, &delays)
+
+	// The frames are padded so the bounce fits, and the component grows to match without moving the image
+	bounds := (*images[0]).Bounds()
+	assert.Equal(t, 40, bounds.Dy())
+	assert.Equal(t, float64(10), component.Position.Y)
+	assert.Equal(t, float64(40), component.Position.Height)
+	// Half way through it's at the top of the bounce, above where the component was
+	assertPixel(t, images[0], bounds.Dx()/2, 28, red)
+	assertPixel(t, images[2], bounds.Dx()/2, 2, red)
+	assertPixel(t, images[2], bounds.Dx()/2, 25, transparent)
+}
+
+func TestGeneratedFrameLimits(t *testing.T) {
+	for frames, expected := range map[float64]int{0: 1, -5: 1, 100000: 200} {
+		for _, name := range []string{"spin", "squash"} {
+			images := solidFrames(1, 20, 20, red)
+			delays := []int{}
+			filters := []*entity.Filter{{Name: name, Arguments: map[string]interface{}{"frames": frames}}}
+			applyFilters(&entity.ImageRequest{}, sizedComponent(0, 0, 20), filters, &images, &delays)
+			assert.Len(t, images, expected, name)
+		}
+	}
+}
+
+func TestSlideEndsInPlace(t *testing.T) {
+	component := sizedComponent(0, 0, 20)
+	images := solidFrames(1, 20, 20, red)
+	delays := []int{}
+	slide := &entity.Filter{Name: "slide", Arguments: map[string]interface{}{"frames": float64(4), "direction": "right"}}
+	Slide{}.AfterStacking(slide, &entity.ImageRequest{Width: 20, Height: 20}, component, &images, &delays)
+
+	// Starts off to the right and ends where it would be without the filter
+	padX := int(-component.Position.X.(float64))
+	assertPixel(t, images[0], padX+10, 10, transparent)
+	assertPixel(t, images[3], padX+1, 1, red)
+	assertPixel(t, images[3], padX+18, 18, red)
+}
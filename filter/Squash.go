@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"github.com/fogleman/gg"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"log"
+	"math"
+)
+
+// Squash squashes the image down and stretches it out over each loop, like it's being patted or bonked.
+// An overlay animation such as res/petpet.gif can be drawn on top, pressing down with the squash
+type Squash struct{}
+
+// Validate checks a local overlay exists, so a missing one is an error rather than squashing without it
+func (s Squash) Validate(args map[string]interface{}) error {
+	overlayURL := helper.GetStringDefault(args["overlay"], "")
+	if overlayURL == "" || !helper.GetBoolDefault(args["overlayLocal"], false) {
+		return nil
+	}
+	_, exception := helper.ResolveResource(overlayURL)
+	return exception
+}
+
+func (s Squash) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	var overlayImages []*image.Image
+	var overlayDelays []int
+	if overlayURL := helper.GetStringDefault(filter.Arguments["overlay"], ""); overlayURL != "" {
+		var exception error
+		overlayImages, overlayDelays, exception = LoadImage(&entity.ImageComponent{
+			URL:   overlayURL,
+			Local: helper.GetBoolDefault(filter.Arguments["overlayLocal"], false),
+		})
+		if exception != nil {
+			log.Println("Unable to get squash overlay:", exception)
+			overlayImages = nil
+		}
+	}
+
+	// The overlay decides the timing, unless told otherwise
+	frameCount := 5
+	delay := 6
+	if len(overlayImages) > 1 {
+		frameCount = len(overlayImages)
+		if len(overlayDelays) > 0 {
+			delay = overlayDelays[0]
+		}
+	}
+	frameCount = frameCountArgument(filter.Arguments["frames"], frameCount)
+	delay = int(helper.GetFloatDefault(filter.Arguments["delay"], float64(delay)))
+
+	squash := helper.GetFloatDefault(filter.Arguments["squash"], 0.2)
+	stretch := helper.GetFloatDefault(filter.Arguments["stretch"], 0.1)
+	// With an overlay the image is shrunk into the bottom right to leave room for it
+	defaultScale := 1.0
+	if overlayImages != nil {
+		defaultScale = 0.8
+	}
+	scale := helper.GetFloatDefault(filter.Arguments["scale"], defaultScale)
+	overlayFollow := helper.GetBoolDefault(filter.Arguments["overlayFollow"], true)
+
+	bounds := (*(*images)[0]).Bounds()
+	width := float64(bounds.Dx())
+	height := float64(bounds.Dy())
+
+	// Stretched out the image can be wider than its box, so the frames are padded to fit it as the motion filters are
+	padX, padY := 0.0, 0.0
+	for _, amount := range []float64{0, 1} {
+		bodyWidth := width * scale * (1 + stretch*amount)
+		bodyHeight := height * scale * (1 - squash*amount)
+		padX = math.Max(padX, math.Max(bodyWidth/2-(width-width*scale/2), (width-width*scale/2)+bodyWidth/2-width))
+		padY = math.Max(padY, bodyHeight-height)
+		if overlayFollow {
+			padY = math.Max(padY, bodyHeight-height*scale)
+		}
+	}
+	padX, padY = math.Ceil(padX), math.Ceil(padY)
+
+	outputImages := make([]*image.Image, frameCount)
+	outputDelay := make([]int, frameCount)
+	for i := range outputImages {
+		// Goes from 0 to 1 and back again over the loop
+		amount := (1 - math.Cos(2*math.Pi*float64(i)/float64(frameCount))) / 2
+		bodyWidth := width * scale * (1 + stretch*amount)
+		bodyHeight := height * scale * (1 - squash*amount)
+
+		ctx := gg.NewContext(bounds.Dx()+int(padX)*2, bounds.Dy()+int(padY)*2)
+		ctx.Translate(padX, padY)
+		// The image stays on the ground, so it squashes down rather than towards the middle
+		ctx.Push()
+		ctx.Translate(width-width*scale/2, height)
+		ctx.Scale(bodyWidth/width, bodyHeight/height)
+		ctx.DrawImageAnchored(*(*images)[i%len(*images)], 0, 0, 0.5, 1)
+		ctx.Pop()
+
+		if overlayImages != nil {
+			overlay := *overlayImages[i%len(overlayImages)]
+			overlayBounds := overlay.Bounds()
+			ctx.Push()
+			if overlayFollow {
+				ctx.Translate(0, height*scale-bodyHeight)
+			}
+			ctx.Scale(width/float64(overlayBounds.Dx()), height/float64(overlayBounds.Dy()))
+			ctx.DrawImage(overlay, 0, 0)
+			ctx.Pop()
+		}
+
+		frame := ctx.Image()
+		outputImages[i] = &frame
+		outputDelay[i] = delay
+	}
+
+	padComponent(request, component, padX, padY, width, height)
+	*images = outputImages
+	*delays = outputDelay
+}
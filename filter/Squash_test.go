@@ -0,0 +1,59 @@
+package filter
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestSquashWithOverlay(t *testing.T) {
+	// An overlay with a bar across the top, which presses down on the image
+	LoadImage = func(component *entity.ImageComponent) ([]*image.Image, []int, error) {
+		overlay := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		draw.Draw(overlay, image.Rect(0, 0, 10, 2), image.NewUniform(blue), image.Point{}, draw.Src)
+		frame := image.Image(overlay)
+		return []*image.Image{&frame}, []int{}, nil
+	}
+	defer func() { LoadImage = nil }()
+
+	images := solidFrames(1, 20, 20, red)
+	delays := []int{}
+	squash := &entity.Filter{Name: "squash", Arguments: map[string]interface{}{"overlay": "overlay.png"}}
+	Squash{}.AfterStacking(squash, &entity.ImageRequest{}, sizedComponent(0, 0, 20), &images, &delays)
+
+	assert.Len(t, images, 5)
+	assert.Equal(t, repeatDelay(6, 5), delays)
+	// The overlay is scaled to the frame and the image sits in the bottom right
+	assertPixel(t, images[0], 1, 1, blue)
+	assertPixel(t, images[0], 1, 10, transparent)
+	assertPixel(t, images[0], 18, 18, red)
+	assertPixel(t, images[0], 10, 5, red)
+	assertPixel(t, images[0], 1, 5, transparent)
+	// Squashed down, the top of the image is lower and the overlay presses down with it
+	assertPixel(t, images[2], 1, 5, blue)
+	assertPixel(t, images[2], 10, 18, red)
+}
+
+func TestSquashStretchIsPadded(t *testing.T) {
+	component := sizedComponent(10, 0, 20)
+	images := solidFrames(1, 20, 20, red)
+	delays := []int{}
+	squash := &entity.Filter{Name: "squash", Arguments: map[string]interface{}{"frames": float64(2)}}
+	Squash{}.AfterStacking(squash, &entity.ImageRequest{Width: 40, Height: 20}, component, &images, &delays)
+
+	// Stretched out to 22 pixels wide, past both sides of the component's box
+	assert.Equal(t, 22, (*images[1]).Bounds().Dx())
+	assert.Equal(t, float64(9), component.Position.X)
+	assertPixel(t, images[1], 0, 19, red)
+	assertPixel(t, images[1], 21, 19, red)
+}
+
+func TestSquashUnknownOverlay(t *testing.T) {
+	exception := Squash{}.Validate(map[string]interface{}{"overlay": "missing.gif", "overlayLocal": true})
+	assert.True(t, errors.Is(exception, helper.ErrUnknownResource))
+	assert.Nil(t, Squash{}.Validate(map[string]interface{}{"overlay": "https://example.com/overlay.gif"}))
+}
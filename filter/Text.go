@@ -1,17 +1,25 @@
 package filter
 
 import (
-	"fmt"
 	"github.com/fogleman/gg"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
-	"image/color"
+	"log"
 	"path"
 	"strings"
 )
 
 type Text struct{}
 
+// Validate checks the font exists, so an unknown font is an error rather than drawn in the default font
+func (r Text) Validate(args map[string]interface{}) error {
+	if args["font"] == nil {
+		return nil
+	}
+	_, exception := helper.ResolveResource(path.Join("font", helper.GetStringDefault(args["font"], "")))
+	return exception
+}
+
 func (r Text) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNum int, component *entity.ImageComponent) *gg.Context {
 
 	evalParams := map[string]interface{}{
@@ -38,7 +46,12 @@ func (r Text) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNu
 	fontSize := helper.ParseFloat(args["fontSize"], 24, evalParams)
 	font := helper.GetStringDefault(args["font"], "arial.ttf")
 
-	_ = ctx.LoadFontFace(path.Join("res/font/", font), fontSize)
+	fontPath, exception := helper.ResolveResource(path.Join("font", font))
+	if exception != nil {
+		log.Println("Unable to load font:", exception)
+	} else {
+		_ = ctx.LoadFontFace(fontPath, fontSize)
+	}
 	if args["shadowColour"] != nil {
 		shadowColour := helper.GetStringDefault(args["shadowColour"], "#000000")
 		ctx.SetHexColor(shadowColour)
@@ -71,7 +84,9 @@ func (r Text) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNu
 		textW, textH := ctx.MeasureMultilineString(strings.Join(wrappedText, "\n"), spacing)
 		textContext := gg.NewContext(int(textW+5), int(textH+30))
 		textContext.SetRGB(0, 0, 0)
-		_ = textContext.LoadFontFace(path.Join("res/font/", font), fontSize)
+		if fontPath != "" {
+			_ = textContext.LoadFontFace(fontPath, fontSize)
+		}
 		textContext.DrawStringWrapped(content, 0, 5, ax, ay, w, spacing, gg.Align(align))
 		textMask := textContext.AsMask()
 
@@ -79,8 +94,7 @@ func (r Text) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNu
 		gradient := args["gradient"].([]interface{})
 		grad := gg.NewLinearGradient(0, 0, 0, fontSize)
 		for i, stop := range gradient {
-			r, g, b, a := parseHexColor(stop.(string))
-			grad.AddColorStop(float64(i), color.RGBA{R: r, G: g, B: b, A: a})
+			grad.AddColorStop(float64(i), helper.ParseHexColour(stop.(string)))
 		}
 		textContext.SetFillStyle(grad)
 
@@ -99,24 +113,3 @@ func (r Text) BeforeRender(ctx *gg.Context, args map[string]interface{}, frameNu
 
 	return ctx
 }
-
-func parseHexColor(x string) (r, g, b, a uint8) {
-	x = strings.TrimPrefix(x, "#")
-	a = 255
-	if len(x) == 3 {
-		format := "%1x%1x%1x"
-		_, _ = fmt.Sscanf(x, format, &r, &g, &b)
-		r |= r << 4
-		g |= g << 4
-		b |= b << 4
-	}
-	if len(x) == 6 {
-		format := "%02x%02x%02x"
-		_, _ = fmt.Sscanf(x, format, &r, &g, &b)
-	}
-	if len(x) == 8 {
-		format := "%02x%02x%02x%02x"
-		_, _ = fmt.Sscanf(x, format, &r, &g, &b, &a)
-	}
-	return
-}
@@ -0,0 +1,14 @@
+package filter
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"testing"
+)
+
+func TestUnknownFont(t *testing.T) {
+	exception := Text{}.Validate(map[string]interface{}{"content": "hi", "font": "../../main.go"})
+	assert.True(t, errors.Is(exception, helper.ErrUnknownResource))
+	assert.Nil(t, Text{}.Validate(map[string]interface{}{"content": "hi"}))
+}
@@ -0,0 +1,180 @@
+package filter
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"log"
+	"math"
+	"sort"
+)
+
+// GIFs can't show a frame for less than 2 centiseconds, most viewers slow them down to 10 instead
+const minimumDelay = 2
+
+type Reverse struct{}
+type Boomerang struct{}
+type Trim struct{}
+type Speed struct{}
+type FPS struct{}
+
+// Reverse plays the animation backwards
+func (r Reverse) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	frameDelays := fullDelays(*images, *delays, helper.GetRequestDelay(request))
+	outputImages := make([]*image.Image, len(*images))
+	outputDelays := make([]int, len(*images))
+	for i := range *images {
+		outputImages[i] = (*images)[len(*images)-1-i]
+		outputDelays[i] = frameDelays[len(*images)-1-i]
+	}
+	*images = outputImages
+	*delays = outputDelays
+}
+
+// Boomerang plays the animation forwards then backwards, without showing the first or last frames twice
+func (b Boomerang) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	if len(*images) < 3 {
+		return
+	}
+	frameDelays := fullDelays(*images, *delays, helper.GetRequestDelay(request))
+	outputImages := append([]*image.Image{}, *images...)
+	outputDelays := append([]int{}, frameDelays...)
+	for i := len(*images) - 2; i > 0; i-- {
+		outputImages = append(outputImages, (*images)[i])
+		outputDelays = append(outputDelays, frameDelays[i])
+	}
+	*images = outputImages
+	*delays = outputDelays
+}
+
+// Trim cuts the animation down to between start and end, which are either frames of the request's default delay
+// or milliseconds like 500ms
+func (t Trim) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	if len(*images) < 2 {
+		return
+	}
+	frameLength := helper.GetRequestDelay(request)
+	frameDelays := fullDelays(*images, *delays, frameLength)
+	starts, duration := frameStarts(frameDelays)
+	start := trimPoint(filter.Arguments["start"], frameLength, duration, 0)
+	end := trimPoint(filter.Arguments["end"], frameLength, duration, duration)
+	if start >= end {
+		log.Println("Trim leaves no frames", start, end)
+		return
+	}
+
+	outputImages := make([]*image.Image, 0)
+	outputDelays := make([]int, 0)
+	for i, frameStart := range starts {
+		frameEnd := frameStart + frameDelays[i]
+		// Frames part way over the start or end are cut short
+		shown := int(math.Min(float64(frameEnd), float64(end)) - math.Max(float64(frameStart), float64(start)))
+		if shown <= 0 {
+			continue
+		}
+		outputImages = append(outputImages, (*images)[i])
+		outputDelays = append(outputDelays, shown)
+	}
+	*images = outputImages
+	*delays = outputDelays
+}
+
+// Speed plays the animation speed times faster, dropping frames that would be shown for less than GIFs allow
+func (s Speed) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	speed := helper.GetFloatDefault(filter.Arguments["speed"], 2)
+	if len(*images) < 2 || speed <= 0 {
+		return
+	}
+	starts, duration := frameStarts(fullDelays(*images, *delays, helper.GetRequestDelay(request)))
+
+	// Work out when each frame is shown at the new speed, keeping only the ones that last long enough
+	duration = int(math.Round(float64(duration) / speed))
+	kept := make([]int, 0)
+	keptStarts := make([]int, 0)
+	for i, start := range starts {
+		scaled := int(math.Round(float64(start) / speed))
+		if len(kept) > 0 && scaled-keptStarts[len(keptStarts)-1] < minimumDelay {
+			continue
+		}
+		kept = append(kept, i)
+		keptStarts = append(keptStarts, scaled)
+	}
+	// The last frame also needs to be long enough
+	if len(kept) > 1 && duration-keptStarts[len(keptStarts)-1] < minimumDelay {
+		kept = kept[:len(kept)-1]
+		keptStarts = keptStarts[:len(keptStarts)-1]
+	}
+
+	*images, *delays = pickFrames(*images, kept, keptStarts, duration)
+}
+
+// FPS resamples the animation to a number of frames per second, at most 50 as that is as fast as a GIF can go
+func (f FPS) AfterStacking(filter *entity.Filter, request *entity.ImageRequest, component *entity.ImageComponent, images *[]*image.Image, delays *[]int) {
+	fps := math.Min(helper.GetFloatDefault(filter.Arguments["fps"], 25), 100/minimumDelay)
+	if len(*images) < 2 || fps <= 0 {
+		return
+	}
+	starts, duration := frameStarts(fullDelays(*images, *delays, helper.GetRequestDelay(request)))
+
+	frameLength := 100 / fps
+	picked := make([]int, 0)
+	pickedStarts := make([]int, 0)
+	for frame := 0; ; frame++ {
+		time := int(math.Round(float64(frame) * frameLength))
+		if time >= duration {
+			break
+		}
+		picked = append(picked, frameAt(starts, time))
+		pickedStarts = append(pickedStarts, time)
+	}
+
+	*images, *delays = pickFrames(*images, picked, pickedStarts, duration)
+}
+
+// fullDelays gets a delay for every frame, treating missing or 0 delays as the request's default delay like the renderer does
+func fullDelays(images []*image.Image, delays []int, defaultDelay int) []int {
+	output := make([]int, len(images))
+	for i := range output {
+		output[i] = defaultDelay
+		if i < len(delays) && delays[i] > 0 {
+			output[i] = delays[i]
+		}
+	}
+	return output
+}
+
+// frameStarts gets when each frame starts and how long the whole animation is
+func frameStarts(delays []int) ([]int, int) {
+	starts := make([]int, len(delays))
+	duration := 0
+	for i, delay := range delays {
+		starts[i] = duration
+		duration += delay
+	}
+	return starts, duration
+}
+
+// frameAt gets the frame being shown at a time
+func frameAt(starts []int, time int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > time }) - 1
+}
+
+// pickFrames builds a new animation out of some of the frames, each shown from its start until the next one's
+func pickFrames(images []*image.Image, picked []int, starts []int, duration int) ([]*image.Image, []int) {
+	outputImages := make([]*image.Image, len(picked))
+	outputDelays := make([]int, len(picked))
+	for i, frame := range picked {
+		outputImages[i] = images[frame]
+		next := duration
+		if i+1 < len(starts) {
+			next = starts[i+1]
+		}
+		outputDelays[i] = next - starts[i]
+	}
+	return outputImages, outputDelays
+}
+
+// trimPoint gets a time in centiseconds within the animation from a number of frames or milliseconds
+func trimPoint(value interface{}, frameLength int, duration int, defaultValue int) int {
+	return int(math.Min(math.Max(float64(helper.GetCentiseconds(value, frameLength, defaultValue)), 0), float64(duration)))
+}
@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"testing"
+)
+
+func TestTimeFilters(t *testing.T) {
+	filters := []*entity.Filter{
+		{Name: "boomerang"},
+		{Name: "speed", Arguments: map[string]interface{}{"speed": float64(2)}},
+	}
+	images := solidFrames(8, 20, 20, red)
+	delays := repeatDelay(4, 8)
+	applyFilters(&entity.ImageRequest{}, sizedComponent(0, 0, 20), filters, &images, &delays)
+	assert.Len(t, images, 14)
+	assert.Equal(t, repeatDelay(2, 14), delays)
+
+	// Faster than a GIF can go, so every other frame is dropped
+	filters[1].Arguments["speed"] = float64(4)
+	images = solidFrames(8, 20, 20, red)
+	delays = repeatDelay(4, 8)
+	applyFilters(&entity.ImageRequest{}, sizedComponent(0, 0, 20), filters, &images, &delays)
+	assert.Len(t, images, 7)
+	assert.Equal(t, repeatDelay(2, 7), delays)
+
+	filters = append(filters, &entity.Filter{Name: "fps", Arguments: map[string]interface{}{"fps": float64(20)}})
+	images = solidFrames(8, 20, 20, red)
+	delays = repeatDelay(4, 8)
+	applyFilters(&entity.ImageRequest{}, sizedComponent(0, 0, 20), filters, &images, &delays)
+	assert.Equal(t, []int{5, 5, 4}, delays)
+}
+
+func TestTrimUsesRequestFrames(t *testing.T) {
+	images := solidFrames(8, 20, 20, red)
+	delays := repeatDelay(4, 8)
+	trim := &entity.Filter{Name: "trim", Arguments: map[string]interface{}{"start": float64(1), "end": "200ms"}}
+	// A frame is the request's 5cs delay, so the trim keeps 5cs to 20cs
+	Trim{}.AfterStacking(trim, &entity.ImageRequest{Delay: 5}, sizedComponent(0, 0, 20), &images, &delays)
+	assert.Len(t, images, 4)
+	assert.Equal(t, []int{3, 4, 4, 4}, delays)
+}
+
+func TestTimeFiltersDefaultDelay(t *testing.T) {
+	images := solidFrames(2, 20, 20, red)
+	delays := []int{}
+	// Frames without a delay are shown for the request's delay
+	Reverse{}.AfterStacking(&entity.Filter{Name: "reverse"}, &entity.ImageRequest{Delay: 5}, sizedComponent(0, 0, 20), &images, &delays)
+	assert.Equal(t, []int{5, 5}, delays)
+}
@@ -0,0 +1,30 @@
+package helper
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// ParseHexColour parses a #rgb, #rrggbb or #rrggbbaa colour
+func ParseHexColour(x string) color.NRGBA {
+	var r, g, b uint8
+	a := uint8(255)
+	x = strings.TrimPrefix(x, "#")
+	if len(x) == 3 {
+		format := "%1x%1x%1x"
+		_, _ = fmt.Sscanf(x, format, &r, &g, &b)
+		r |= r << 4
+		g |= g << 4
+		b |= b << 4
+	}
+	if len(x) == 6 {
+		format := "%02x%02x%02x"
+		_, _ = fmt.Sscanf(x, format, &r, &g, &b)
+	}
+	if len(x) == 8 {
+		format := "%02x%02x%02x%02x"
+		_, _ = fmt.Sscanf(x, format, &r, &g, &b, &a)
+	}
+	return color.NRGBA{R: r, G: g, B: b, A: a}
+}
@@ -0,0 +1,13 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestParseHexColour(t *testing.T) {
+	assert.Equal(t, color.NRGBA{R: 255, G: 0, B: 136, A: 255}, ParseHexColour("#f08"))
+	assert.Equal(t, color.NRGBA{R: 18, G: 52, B: 86, A: 255}, ParseHexColour("123456"))
+	assert.Equal(t, color.NRGBA{R: 18, G: 52, B: 86, A: 120}, ParseHexColour("#12345678"))
+}
@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// The control points of the named CSS easing curves
+var easingCurves = map[string][4]float64{
+	"ease":        {0.25, 0.1, 0.25, 1},
+	"ease-in":     {0.42, 0, 1, 1},
+	"ease-out":    {0, 0, 0.58, 1},
+	"ease-in-out": {0.42, 0, 0.58, 1},
+}
+
+// Ease maps progress from 0 to 1 through an easing curve: linear, ease, ease-in, ease-out, ease-in-out,
+// cubic-bezier(x1, y1, x2, y2), bounce, step (holding until the end) or steps(n)
+func Ease(easing string, t float64) float64 {
+	t = math.Max(0, math.Min(1, t))
+	easing = strings.ReplaceAll(strings.ToLower(easing), " ", "")
+
+	if curve, ok := easingCurves[easing]; ok {
+		return cubicBezier(curve, t)
+	}
+
+	var x1, y1, x2, y2 float64
+	if _, exception := fmt.Sscanf(easing, "cubic-bezier(%g,%g,%g,%g)", &x1, &y1, &x2, &y2); exception == nil {
+		return cubicBezier([4]float64{math.Max(0, math.Min(1, x1)), y1, math.Max(0, math.Min(1, x2)), y2}, t)
+	}
+
+	var steps int
+	if _, exception := fmt.Sscanf(easing, "steps(%d)", &steps); exception == nil && steps > 0 {
+		return math.Floor(t*float64(steps)) / float64(steps)
+	}
+
+	switch easing {
+	case "", "linear":
+		return t
+	case "step":
+		return math.Floor(t)
+	case "bounce":
+		return bounce(t)
+	}
+	log.Println("Unknown easing", easing)
+	return t
+}
+
+// cubicBezier finds the x of the curve that matches t, then returns the y there
+func cubicBezier(curve [4]float64, t float64) float64 {
+	at := func(p1 float64, p2 float64, s float64) float64 {
+		return 3*p1*s*(1-s)*(1-s) + 3*p2*s*s*(1-s) + s*s*s
+	}
+	// x always increases along the curve, so a binary search finds the right point
+	low, high := 0.0, 1.0
+	s := t
+	for i := 0; i < 30; i++ {
+		if at(curve[0], curve[2], s) < t {
+			low = s
+		} else {
+			high = s
+		}
+		s = (low + high) / 2
+	}
+	return at(curve[1], curve[3], s)
+}
+
+// bounce is the standard ease-out bounce, dropping to 1 and bouncing three times
+func bounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + 0.984375
+	}
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEase(t *testing.T) {
+	for _, easing := range []string{"linear", "ease", "ease-in", "ease-out", "ease-in-out", "cubic-bezier(0.1, 0.7, 1, 0.1)", "bounce", "steps(4)"} {
+		assert.InDelta(t, 0, Ease(easing, 0), 0.001, easing)
+		assert.InDelta(t, 1, Ease(easing, 1), 0.001, easing)
+	}
+	assert.Equal(t, 0.5, Ease("linear", 0.5))
+	assert.Less(t, Ease("ease-in", 0.5), 0.5)
+	assert.Greater(t, Ease("ease-out", 0.5), 0.5)
+	assert.InDelta(t, 0.5, Ease("ease-in-out", 0.5), 0.001)
+	assert.Equal(t, 0.0, Ease("step", 0.99))
+	assert.Equal(t, 0.5, Ease("steps(4)", 0.6))
+}
@@ -0,0 +1,125 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnknownResource is returned when a resource is requested that isn't in the manifest
+var ErrUnknownResource = errors.New("unknown resource")
+
+var resourceRoot = "res"
+
+// The manifest of every usable resource, keyed by its name relative to resourceRoot
+var resources = map[string]*entity.Resource{}
+
+// LoadResources walks the resource directory and builds the manifest of available images and fonts.
+// Only files in the manifest can be loaded, so nothing outside of the directory can ever be reached
+func LoadResources(root string) error {
+	manifest := map[string]*entity.Resource{}
+	exception := filepath.Walk(root, func(filePath string, info os.FileInfo, exception error) error {
+		if exception != nil {
+			return exception
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relative, exception := filepath.Rel(root, filePath)
+		if exception != nil {
+			return exception
+		}
+
+		resource, exception := describeResource(filePath, filepath.ToSlash(relative))
+		if exception != nil {
+			return exception
+		}
+		if resource != nil {
+			manifest[resource.Name] = resource
+		}
+		return nil
+	})
+
+	if exception != nil {
+		return exception
+	}
+
+	resourceRoot = root
+	resources = manifest
+	return nil
+}
+
+// describeResource builds the manifest entry for a file, or nil if it isn't an image or font
+func describeResource(filePath string, name string) (*entity.Resource, error) {
+	contents, exception := ioutil.ReadFile(filePath)
+	if exception != nil {
+		return nil, exception
+	}
+
+	checksum := sha256.Sum256(contents)
+	resource := &entity.Resource{
+		Name:     name,
+		Size:     int64(len(contents)),
+		Checksum: hex.EncodeToString(checksum[:]),
+	}
+
+	extension := strings.ToLower(path.Ext(name))
+	switch extension {
+	case ".ttf", ".otf":
+		resource.Type = "font"
+		resource.Format = extension[1:]
+		return resource, nil
+	case ".svg":
+		resource.Type = "image"
+		resource.Format = "svg"
+		return resource, nil
+	}
+
+	config, format, exception := image.DecodeConfig(bytes.NewReader(contents))
+	if exception != nil {
+		return nil, nil
+	}
+	resource.Type = "image"
+	resource.Format = format
+	resource.Width = config.Width
+	resource.Height = config.Height
+	return resource, nil
+}
+
+// ResolveResource gets the path to a resource by name, erroring if it isn't in the manifest.
+// Names are cleaned as if they were absolute first, so ../ can't climb out of the resource directory
+func ResolveResource(name string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if _, ok := resources[cleaned]; !ok {
+		return "", fmt.Errorf("%w '%s'", ErrUnknownResource, name)
+	}
+	return filepath.Join(resourceRoot, filepath.FromSlash(cleaned)), nil
+}
+
+// GetResources returns the manifest, sorted by name
+func GetResources() []*entity.Resource {
+	output := make([]*entity.Resource, 0, len(resources))
+	for _, resource := range resources {
+		output = append(output, resource)
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Name < output[j].Name
+	})
+	return output
+}
@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveResource(t *testing.T) {
+	root, exception := ioutil.TempDir("", "resources")
+	if exception != nil {
+		t.Fatal(exception)
+	}
+	defer os.RemoveAll(root)
+
+	_ = os.Mkdir(filepath.Join(root, "font"), 0755)
+	_ = ioutil.WriteFile(filepath.Join(root, "font", "arial.ttf"), []byte("font"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(root, "notes.txt"), []byte("not a resource"), 0644)
+	file, _ := os.Create(filepath.Join(root, "square.png"))
+	_ = png.Encode(file, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+	_ = file.Close()
+
+	assert.NoError(t, LoadResources(root))
+
+	resources := GetResources()
+	assert.Len(t, resources, 2)
+	assert.Equal(t, "font/arial.ttf", resources[0].Name)
+	assert.Equal(t, "font", resources[0].Type)
+	assert.Equal(t, "square.png", resources[1].Name)
+	assert.Equal(t, 3, resources[1].Width)
+	assert.Equal(t, 2, resources[1].Height)
+	assert.Len(t, resources[1].Checksum, 64)
+
+	resolved, exception := ResolveResource("square.png")
+	assert.NoError(t, exception)
+	assert.Equal(t, filepath.Join(root, "square.png"), resolved)
+
+	resolved, exception = ResolveResource("font/../font/arial.ttf")
+	assert.NoError(t, exception)
+	assert.Equal(t, filepath.Join(root, "font", "arial.ttf"), resolved)
+
+	for _, name := range []string{"notes.txt", "../square.png/../../etc/passwd", "../../etc/passwd", "missing.png"} {
+		_, exception = ResolveResource(name)
+		assert.True(t, errors.Is(exception, ErrUnknownResource), name)
+	}
+}
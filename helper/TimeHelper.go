@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultFrameDelay is the delay of a frame when neither it nor the request has one, in centiseconds
+const DefaultFrameDelay = 10
+
+// GetRequestDelay gets the request's default delay, from its delay or frame rate if it has one.
+// This is the length of a frame whenever a time is given as a number of frames
+func GetRequestDelay(request *entity.ImageRequest) int {
+	if request.Delay > 0 {
+		return request.Delay
+	}
+	if request.FPS > 0 {
+		return int(math.Max(1, math.Round(100/request.FPS)))
+	}
+	return DefaultFrameDelay
+}
+
+// GetCentiseconds gets a time in centiseconds from either a number of frames of frameLength centiseconds each,
+// or a string of milliseconds such as 500ms
+func GetCentiseconds(value interface{}, frameLength int, defaultValue int) int {
+	switch cast := value.(type) {
+	case float64:
+		return int(cast * float64(frameLength))
+	case int:
+		return cast * frameLength
+	case string:
+		if strings.HasSuffix(cast, "ms") {
+			milliseconds, exception := strconv.ParseFloat(strings.TrimSuffix(cast, "ms"), 64)
+			if exception == nil {
+				return int(math.Round(milliseconds / 10))
+			}
+		} else if frames, exception := strconv.ParseFloat(cast, 64); exception == nil {
+			return int(frames * float64(frameLength))
+		}
+		log.Println("Invalid time", cast)
+	}
+	return defaultValue
+}
@@ -0,0 +1,15 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetCentiseconds(t *testing.T) {
+	assert.Equal(t, 30, GetCentiseconds(float64(3), 10, 0))
+	assert.Equal(t, 15, GetCentiseconds(float64(3), 5, 0))
+	assert.Equal(t, 50, GetCentiseconds("500ms", 10, 0))
+	assert.Equal(t, 20, GetCentiseconds("2", 10, 0))
+	assert.Equal(t, -1, GetCentiseconds(nil, 10, -1))
+	assert.Equal(t, -1, GetCentiseconds("soon", 10, -1))
+}
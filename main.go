@@ -11,6 +11,7 @@ import (
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/streadway/amqp"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
 	"golang.org/x/image/webp"
 	"image"
 	"log"
@@ -45,6 +46,13 @@ func main() {
 
 	image.RegisterFormat("webp", "RIFF", webp.Decode, webp.DecodeConfig)
 
+	exception := helper.LoadResources("res")
+	if exception != nil {
+		sentry.CaptureException(exception)
+		log.Fatalf("Failed to load resources: %s", exception)
+	}
+	log.Println("Loaded resource manifest: ", len(helper.GetResources()))
+
 	conn, exception := amqp.Dial(os.Getenv("RABBIT_URL"))
 
 	if exception != nil {
@@ -113,6 +121,7 @@ func main() {
 
 	http.Handle("/metrics", promhttp.Handler())
 	http.Handle("/healthz", http.HandlerFunc(handleHealthRequest))
+	http.Handle("/resources", http.HandlerFunc(handleResourcesRequest))
 	http.Handle("/output/", logHttpRequests(http.StripPrefix("/output", http.FileServer(http.Dir(path.Join(wd, "output"))))))
 	_ = http.ListenAndServe(":2112", nil)
 
@@ -135,6 +144,17 @@ func handleHealthRequest(writer http.ResponseWriter, request *http.Request) {
 	writer.Write([]byte(fmt.Sprintf("Completion ratio: %.2f", ratio)))
 }
 
+func handleResourcesRequest(writer http.ResponseWriter, request *http.Request) {
+	output, exception := json.Marshal(helper.GetResources())
+	if exception != nil {
+		sentry.CaptureException(exception)
+		writer.WriteHeader(500)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write(output)
+}
+
 func logHttpRequests(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		requestsHandled.Inc()
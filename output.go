@@ -14,6 +14,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/stage"
 	"image"
 	"image/color"
 	"image/gif"
@@ -62,11 +63,7 @@ func OutputImage(input []image.Image, delay []int, frameDisposal bool, request *
 		for frame, img := range input {
 			wg.Add(1)
 			go quantizeWorker(frame, img, &wg, images)
-			if frameDisposal {
-				disposal[frame] = gif.DisposalBackground
-			} else {
-				disposal[frame] = gif.DisposalNone
-			}
+			disposal[frame] = stage.GIFDisposal(request, frame, !frameDisposal)
 
 		}
 
@@ -85,7 +82,7 @@ func OutputImage(input []image.Image, delay []int, frameDisposal bool, request *
 			Image:           images,
 			Delay:           delay,
 			Disposal:        disposal,
-			LoopCount:       0,
+			LoopCount:       stage.GIFLoopCount(request),
 			BackgroundIndex: 0,
 			Config:          config,
 		}
@@ -0,0 +1,220 @@
+package stage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// APNG dispose_op and blend_op values from the fcTL chunk
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendSource       = 0
+	apngBlendOver         = 1
+)
+
+type pngChunk struct {
+	chunkType string
+	data      []byte
+}
+
+// apngFrame is a single fcTL chunk along with the image data that belongs to it
+type apngFrame struct {
+	width     int
+	height    int
+	x         int
+	y         int
+	delay     int
+	dispose   byte
+	blend     byte
+	data      []byte
+	isDefault bool
+}
+
+// readPNGChunks splits a PNG file into its chunks, without checking the CRCs
+func readPNGChunks(body []byte) ([]pngChunk, error) {
+	if len(body) < len(pngSignature) || string(body[:len(pngSignature)]) != pngSignature {
+		return nil, errors.New("png: invalid signature")
+	}
+	chunks := make([]pngChunk, 0)
+	offset := len(pngSignature)
+	for offset+8 <= len(body) {
+		length := int(binary.BigEndian.Uint32(body[offset : offset+4]))
+		chunkType := string(body[offset+4 : offset+8])
+		if length < 0 || offset+12+length > len(body) {
+			return nil, errors.New("png: truncated chunk " + chunkType)
+		}
+		chunks = append(chunks, pngChunk{chunkType: chunkType, data: body[offset+8 : offset+8+length]})
+		offset += 12 + length
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// isAnimatedPNG checks for an acTL chunk, which has to come before the first IDAT
+func isAnimatedPNG(body []byte) bool {
+	chunks, err := readPNGChunks(body)
+	if err != nil {
+		return false
+	}
+	for _, chunk := range chunks {
+		switch chunk.chunkType {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+	}
+	return false
+}
+
+// decodeAPNG decodes every frame of an animated PNG and composites them according to their blend and dispose ops
+func decodeAPNG(body []byte) ([]*image.Image, []int, error) {
+	log.Println("Decoding the apng...")
+	chunks, err := readPNGChunks(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var header []byte
+	// Chunks such as PLTE and tRNS need to be copied into every frame for it to decode the same way
+	sharedChunks := make([]pngChunk, 0)
+	frames := make([]*apngFrame, 0)
+	var current *apngFrame
+	seenData := false
+
+	for _, chunk := range chunks {
+		switch chunk.chunkType {
+		case "IHDR":
+			header = chunk.data
+		case "acTL", "IEND":
+		case "fcTL":
+			if len(chunk.data) < 26 {
+				return nil, nil, errors.New("apng: invalid fcTL chunk")
+			}
+			current = &apngFrame{
+				width:     int(binary.BigEndian.Uint32(chunk.data[4:8])),
+				height:    int(binary.BigEndian.Uint32(chunk.data[8:12])),
+				x:         int(binary.BigEndian.Uint32(chunk.data[12:16])),
+				y:         int(binary.BigEndian.Uint32(chunk.data[16:20])),
+				delay:     apngDelay(binary.BigEndian.Uint16(chunk.data[20:22]), binary.BigEndian.Uint16(chunk.data[22:24])),
+				dispose:   chunk.data[24],
+				blend:     chunk.data[25],
+				isDefault: !seenData,
+			}
+			frames = append(frames, current)
+		case "IDAT":
+			seenData = true
+			// An IDAT without a preceding fcTL is the default image, which isn't part of the animation
+			if current != nil && current.isDefault {
+				current.data = append(current.data, chunk.data...)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.data) > 4 {
+				current.data = append(current.data, chunk.data[4:]...)
+			}
+		default:
+			if !seenData {
+				sharedChunks = append(sharedChunks, chunk)
+			}
+		}
+	}
+
+	if len(header) < 13 || len(frames) == 0 {
+		return nil, nil, errors.New("apng: no frames")
+	}
+
+	screenWidth := int(binary.BigEndian.Uint32(header[0:4]))
+	screenHeight := int(binary.BigEndian.Uint32(header[4:8]))
+	if err := checkCanvasSize("apng", screenWidth, screenHeight); err != nil {
+		return nil, nil, err
+	}
+	compositor := newFrameCompositor(screenWidth, screenHeight)
+
+	output := make([]*image.Image, 0, len(frames))
+	delays := make([]int, 0, len(frames))
+	for i, frame := range frames {
+		frameImage, err := png.Decode(bytes.NewReader(buildPNGFrame(header, sharedChunks, frame)))
+		if err != nil {
+			log.Printf("Error decoding apng frame %d: %s\n", i, err)
+			return nil, nil, err
+		}
+
+		rect := image.Rect(frame.x, frame.y, frame.x+frame.width, frame.y+frame.height)
+		dispose := frame.dispose
+		// The spec says to treat APNG_DISPOSE_OP_PREVIOUS on the first frame as APNG_DISPOSE_OP_BACKGROUND
+		if i == 0 && dispose == apngDisposePrevious {
+			dispose = apngDisposeBackground
+		}
+		if dispose == apngDisposePrevious {
+			compositor.save(rect)
+		}
+
+		compositor.drawImage(frameImage, rect, frame.blend == apngBlendOver)
+		output = append(output, compositor.snapshot())
+		delays = append(delays, frame.delay)
+
+		switch dispose {
+		case apngDisposeBackground:
+			compositor.clear(rect, color.Transparent)
+		case apngDisposePrevious:
+			compositor.restore()
+		}
+	}
+
+	return output, delays, nil
+}
+
+// apngDelay converts an APNG delay fraction (in seconds) to centiseconds, the unit GIFs use
+func apngDelay(numerator uint16, denominator uint16) int {
+	if denominator == 0 {
+		denominator = 100
+	}
+	return int(numerator) * 100 / int(denominator)
+}
+
+// buildPNGFrame makes a standalone PNG out of a single APNG frame so it can be decoded by image/png
+func buildPNGFrame(header []byte, sharedChunks []pngChunk, frame *apngFrame) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(pngSignature)
+
+	frameHeader := make([]byte, len(header))
+	copy(frameHeader, header)
+	binary.BigEndian.PutUint32(frameHeader[0:4], uint32(frame.width))
+	binary.BigEndian.PutUint32(frameHeader[4:8], uint32(frame.height))
+	writePNGChunk(buf, "IHDR", frameHeader)
+
+	for _, chunk := range sharedChunks {
+		writePNGChunk(buf, chunk.chunkType, chunk.data)
+	}
+	writePNGChunk(buf, "IDAT", frame.data)
+	writePNGChunk(buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func writePNGChunk(buf *bytes.Buffer, chunkType string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	buf.WriteString(chunkType)
+	buf.Write(data)
+
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc.Sum32())
+	buf.Write(checksum[:])
+}
@@ -0,0 +1,69 @@
+package stage
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestDecodeAPNG(t *testing.T) {
+	frames := decodeFixture(t, "animated.png")
+	assert.Len(t, frames, 3)
+	assertPixel(t, frames[0], 3, 3, red)
+	// The half transparent blue frame is blended over the red one
+	assertPixel(t, frames[1], 3, 3, color.RGBA{R: 127, B: 128, A: 255})
+	assertPixel(t, frames[1], 0, 0, red)
+	// The blue frame is disposed back to the red underneath it before the last frame
+	assertPixel(t, frames[2], 0, 0, green)
+	assertPixel(t, frames[2], 3, 3, red)
+}
+
+func TestDecodeAnimatedWebP(t *testing.T) {
+	file, err := os.Open(path.Join("testdata", "animated.webp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	frames, delays, err := getImage(file, &entity.ImageComponent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, frames, 2)
+	assert.Equal(t, []int{10, 20}, delays)
+	assert.Equal(t, image.Rect(0, 0, 150, 100), (*frames[0]).Bounds())
+	// The second frame is the same image drawn to the right of the first
+	for _, point := range []image.Point{{X: 10, Y: 10}, {X: 40, Y: 50}, {X: 70, Y: 90}} {
+		assert.Equal(t, (*frames[0]).At(point.X, point.Y), (*frames[1]).At(point.X+75, point.Y))
+	}
+	assertPixel(t, frames[0], 100, 50, transparent)
+}
+
+func TestDecodeOversizedCanvas(t *testing.T) {
+	body, err := os.ReadFile(path.Join("testdata", "animated.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Widen the IHDR past the limit, which would otherwise be allocated for every frame
+	binary.BigEndian.PutUint32(body[16:20], _maxSVGSize+1)
+	_, _, err = decodeAPNG(body)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "canvas size")
+	}
+
+	body, err = os.ReadFile(path.Join("testdata", "animated.webp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The VP8X canvas width is stored minus one
+	putUint24(body[24:27], _maxSVGSize)
+	_, _, err = decodeAnimatedWebP(body)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "canvas size")
+	}
+}
@@ -0,0 +1,92 @@
+package stage
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// ErrUnknownBlend is returned when a component's blend mode doesn't exist
+var ErrUnknownBlend = errors.New("unknown blend mode")
+
+// blendModes are the separable blend functions from the W3C compositing spec, taking the backdrop and source
+// colour channels (non-premultiplied, 0 to 1) and returning the mixed colour
+var blendModes = map[string]func(backdrop float64, source float64) float64{
+	"multiply": func(backdrop float64, source float64) float64 {
+		return backdrop * source
+	},
+	"screen": blendScreen,
+	"overlay": func(backdrop float64, source float64) float64 {
+		// Overlay is hard-light with the layers swapped
+		if backdrop <= 0.5 {
+			return source * 2 * backdrop
+		}
+		return blendScreen(source, 2*backdrop-1)
+	},
+	"darken":  math.Min,
+	"lighten": math.Max,
+	"difference": func(backdrop float64, source float64) float64 {
+		return math.Abs(backdrop - source)
+	},
+	"add": func(backdrop float64, source float64) float64 {
+		return math.Min(1, backdrop+source)
+	},
+	"soft-light": func(backdrop float64, source float64) float64 {
+		if source <= 0.5 {
+			return backdrop - (1-2*source)*backdrop*(1-backdrop)
+		}
+		var d float64
+		if backdrop <= 0.25 {
+			d = ((16*backdrop-12)*backdrop + 4) * backdrop
+		} else {
+			d = math.Sqrt(backdrop)
+		}
+		return backdrop + (2*source-1)*(d-backdrop)
+	},
+}
+
+func blendScreen(backdrop float64, source float64) float64 {
+	return backdrop + source - backdrop*source
+}
+
+// validateBlend checks the blend mode is normal or one of the blend modes
+func validateBlend(mode string) error {
+	if _, ok := blendModes[mode]; !ok && mode != "" && mode != "normal" {
+		return fmt.Errorf("%w '%s'", ErrUnknownBlend, mode)
+	}
+	return nil
+}
+
+// isBlended returns true if the blend mode needs anything other than normal source-over compositing
+func isBlended(mode string) bool {
+	_, ok := blendModes[mode]
+	return ok
+}
+
+// blendImage composites source onto backdrop using the blend mode. Both images must be the same size
+func blendImage(backdrop *image.RGBA, source *image.RGBA, mode string) {
+	blend := blendModes[mode]
+	for i := 0; i+3 < len(source.Pix) && i+3 < len(backdrop.Pix); i += 4 {
+		sourceAlpha := float64(source.Pix[i+3]) / 255
+		if sourceAlpha == 0 {
+			continue
+		}
+		backdropAlpha := float64(backdrop.Pix[i+3]) / 255
+
+		for c := 0; c < 3; c++ {
+			// Both images are premultiplied, the blend functions need straight colour
+			premultipliedSource := float64(source.Pix[i+c]) / 255
+			premultipliedBackdrop := float64(backdrop.Pix[i+c]) / 255
+			sourceColour := premultipliedSource / sourceAlpha
+			backdropColour := 0.0
+			if backdropAlpha > 0 {
+				backdropColour = premultipliedBackdrop / backdropAlpha
+			}
+
+			mixed := premultipliedSource*(1-backdropAlpha) + premultipliedBackdrop*(1-sourceAlpha) + sourceAlpha*backdropAlpha*blend(backdropColour, sourceColour)
+			backdrop.Pix[i+c] = uint8(math.Round(math.Max(0, math.Min(1, mixed)) * 255))
+		}
+		backdrop.Pix[i+3] = uint8(math.Round((sourceAlpha + backdropAlpha*(1-sourceAlpha)) * 255))
+	}
+}
@@ -0,0 +1,52 @@
+package stage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBlendImage(t *testing.T) {
+	cases := map[string]color.RGBA{
+		"multiply":   {R: 128, G: 0, B: 0, A: 255},
+		"screen":     {R: 255, G: 128, B: 0, A: 255},
+		"darken":     {R: 128, G: 0, B: 0, A: 255},
+		"lighten":    {R: 255, G: 128, B: 0, A: 255},
+		"difference": {R: 127, G: 128, B: 0, A: 255},
+		"add":        {R: 255, G: 128, B: 0, A: 255},
+	}
+	for mode, expected := range cases {
+		backdrop := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		backdrop.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+		source := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		source.SetRGBA(0, 0, color.RGBA{R: 128, G: 128, A: 255})
+
+		blendImage(backdrop, source, mode)
+		assert.Equal(t, expected, backdrop.RGBAAt(0, 0), mode)
+	}
+}
+
+func TestBlendImageTransparentBackdrop(t *testing.T) {
+	// Over nothing, every blend mode is the same as drawing the source normally
+	backdrop := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	source := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	source.SetRGBA(0, 0, color.RGBA{R: 64, G: 32, A: 128})
+
+	blendImage(backdrop, source, "multiply")
+	assert.Equal(t, color.RGBA{R: 64, G: 32, A: 128}, backdrop.RGBAAt(0, 0))
+}
+
+func TestUnknownBlend(t *testing.T) {
+	component := square(0, 0, 10, "#ff0000")
+	component.Blend = "sparkle"
+	_, _, _, err := Render(&entity.ImageRequest{ImageComponents: []*entity.ImageComponent{component}})
+	assert.True(t, errors.Is(err, ErrUnknownBlend))
+
+	component = square(0, 0, 10, "#ff0000")
+	component.Blend = "normal"
+	_, _, _, err = Render(&entity.ImageRequest{ImageComponents: []*entity.ImageComponent{component}})
+	assert.Nil(t, err)
+}
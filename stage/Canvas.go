@@ -0,0 +1,79 @@
+package stage
+
+import (
+	"github.com/fogleman/gg"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"log"
+	"math"
+)
+
+// AutoSizeCanvas sizes the canvas to the area covered by every component once rotated or transformed,
+// plus the padding, moving the components so the top left one sits at the padding
+func AutoSizeCanvas(request *entity.ImageRequest) {
+	if !request.AutoSize {
+		return
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, component := range request.ImageComponents {
+		width, _ := component.Position.Width.(float64)
+		height, _ := component.Position.Height.(float64)
+		if width == 0 && height == 0 {
+			continue
+		}
+		componentMinX, componentMinY, componentMaxX, componentMaxY := componentBounds(component)
+		minX = math.Min(minX, componentMinX)
+		minY = math.Min(minY, componentMinY)
+		maxX = math.Max(maxX, componentMaxX)
+		maxY = math.Max(maxY, componentMaxY)
+	}
+	if math.IsInf(minX, 1) {
+		log.Println("No components to size the canvas from")
+		return
+	}
+
+	offsetX := request.Padding - math.Floor(minX)
+	offsetY := request.Padding - math.Floor(minY)
+	moveComponents(request.ImageComponents, offsetX, offsetY)
+
+	request.Width = int(math.Ceil(maxX + offsetX + request.Padding))
+	request.Height = int(math.Ceil(maxY + offsetY + request.Padding))
+	log.Printf("Auto sized canvas to %dx%d\n", request.Width, request.Height)
+}
+
+// drawCanvasBackground fills the canvas with the background colour, or the gradient if there is one
+func drawCanvasBackground(ctx *gg.Context, request *entity.ImageRequest) {
+	width := float64(ctx.Width())
+	height := float64(ctx.Height())
+	if len(request.Gradient) == 0 {
+		if request.Background == "" {
+			return
+		}
+		ctx.SetHexColor(request.Background)
+	} else if len(request.Gradient) == 1 {
+		ctx.SetColor(helper.ParseHexColour(request.Gradient[0]))
+	} else {
+		// The gradient runs through the centre, and is long enough to reach the corners at any angle
+		angle := 180.0
+		if request.GradientAngle != nil {
+			angle = *request.GradientAngle
+		}
+		// Angles are clockwise from pointing up, and y goes down the canvas
+		sin, cos := math.Sincos(angle * math.Pi / 180)
+		directionX, directionY := sin, -cos
+		length := math.Abs(width*directionX) + math.Abs(height*directionY)
+		centreX, centreY := width/2, height/2
+		gradient := gg.NewLinearGradient(
+			centreX-directionX*length/2, centreY-directionY*length/2,
+			centreX+directionX*length/2, centreY+directionY*length/2,
+		)
+		for i, colour := range request.Gradient {
+			gradient.AddColorStop(float64(i)/float64(len(request.Gradient)-1), helper.ParseHexColour(colour))
+		}
+		ctx.SetFillStyle(gradient)
+	}
+	ctx.DrawRectangle(0, 0, width, height)
+	ctx.Fill()
+}
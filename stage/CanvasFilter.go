@@ -0,0 +1,57 @@
+package stage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fogleman/gg"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/filter"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+)
+
+// ErrUnknownFilter is returned when the request's filters include one that doesn't exist
+var ErrUnknownFilter = errors.New("unknown filter")
+
+// validateCanvasFilters checks every one of the request's filters exists and has valid arguments
+func validateCanvasFilters(request *entity.ImageRequest) error {
+	for _, filterData := range request.Filters {
+		if _, ok := filter.Filters[filterData.Name]; !ok {
+			return fmt.Errorf("%w '%s'", ErrUnknownFilter, filterData.Name)
+		}
+	}
+	return validateFilters(request.Filters)
+}
+
+// processCanvasFilters applies the request's filters to the finished frames, as if the canvas was a component
+// covering the whole image. BeforeStacking filters run first, then AfterStacking filters can change the frames and
+// delays, then BeforeRender filters draw onto each frame
+func processCanvasFilters(request *entity.ImageRequest, outputContexts []*gg.Context, outputDelay []int) ([]*gg.Context, []int) {
+	canvas := &entity.ImageComponent{
+		Position: entity.Position{X: float64(0), Y: float64(0), Width: float64(request.Width), Height: float64(request.Height)},
+		Filters:  request.Filters,
+	}
+
+	frameImages := make([]*image.Image, len(outputContexts))
+	for i, ctx := range outputContexts {
+		frameImage := ctx.Image()
+		frameImages[i] = &frameImage
+	}
+	frameDelay := append([]int{}, outputDelay...)
+
+	processComponentBeforeStackingFilters(request, canvas)
+	processAfterStackingFilters(request, canvas, &frameImages, &frameDelay)
+
+	filteredContexts := make([]*gg.Context, len(frameImages))
+	filteredDelay := make([]int, len(frameImages))
+	for i, frameImage := range frameImages {
+		// Filters can repeat the same frame, so each one is copied before it's drawn on
+		filteredContexts[i] = gg.NewContextForImage(*frameImage)
+		processBeforeRenderFilters(filteredContexts[i], canvas, i)
+		filteredDelay[i] = helper.GetRequestDelay(request)
+		if i < len(frameDelay) {
+			filteredDelay[i] = frameDelay[i]
+		}
+	}
+	return filteredContexts, filteredDelay
+}
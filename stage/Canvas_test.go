@@ -0,0 +1,64 @@
+package stage
+
+import (
+	"github.com/fogleman/gg"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+func TestAutoSizeCanvas(t *testing.T) {
+	request := &entity.ImageRequest{
+		Width:    500,
+		AutoSize: true,
+		Padding:  5,
+		ImageComponents: []*entity.ImageComponent{
+			{Position: entity.Position{X: float64(20), Y: float64(30), Width: float64(40), Height: float64(20)}},
+			// Rotated 90 degrees about its centre, so it covers 100,90 to 120,130
+			{Position: entity.Position{X: float64(90), Y: float64(100), Width: float64(40), Height: float64(20)}, Rotation: 90, RotationUnit: "deg"},
+		},
+	}
+	AutoSizeCanvas(request)
+	assert.Equal(t, 110, request.Width)
+	assert.Equal(t, 110, request.Height)
+	assert.Equal(t, float64(5), request.ImageComponents[0].Position.X)
+	assert.Equal(t, float64(5), request.ImageComponents[0].Position.Y)
+	assert.Equal(t, float64(75), request.ImageComponents[1].Position.X)
+}
+
+func TestAutoSizeTransformedCanvas(t *testing.T) {
+	component := square(0, 0, 10, "#ff0000")
+	component.Transform = &entity.Transform{Matrix: []float64{1, 0, 0, 1, 50, 50}}
+	frame := renderFrame(t, &entity.ImageRequest{AutoSize: true, ImageComponents: []*entity.ImageComponent{component}})
+	assert.Equal(t, image.Rect(0, 0, 10, 10), (*frame).Bounds())
+	assertPixel(t, frame, 5, 5, red)
+}
+
+func TestCanvasGradient(t *testing.T) {
+	gradientEnds := func(angle *float64, fromX, fromY, toX, toY int) {
+		ctx := gg.NewContext(100, 100)
+		drawCanvasBackground(ctx, &entity.ImageRequest{Gradient: []string{"#ff0000", "#0000ff"}, GradientAngle: angle})
+		frame := ctx.Image().(*image.RGBA)
+		assert.Greater(t, frame.RGBAAt(fromX, fromY).R, uint8(250))
+		assert.Greater(t, frame.RGBAAt(toX, toY).B, uint8(250))
+	}
+	up, right, left := 0.0, 90.0, 270.0
+	// Like CSS, without an angle the gradient goes to the bottom
+	gradientEnds(nil, 50, 0, 50, 99)
+	gradientEnds(&up, 50, 99, 50, 0)
+	gradientEnds(&right, 0, 50, 99, 50)
+	gradientEnds(&left, 99, 50, 0, 50)
+}
+
+func TestCanvasBackgroundRenders(t *testing.T) {
+	frame := renderFrame(t, &entity.ImageRequest{
+		AutoSize:        true,
+		Padding:         10,
+		Background:      "#00ff00",
+		ImageComponents: []*entity.ImageComponent{square(50, 50, 10, "#ff0000")},
+	})
+	assert.Equal(t, image.Rect(0, 0, 30, 30), (*frame).Bounds())
+	assertPixel(t, frame, 5, 5, green)
+	assertPixel(t, frame, 15, 15, red)
+}
@@ -0,0 +1,107 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"golang.org/x/image/draw"
+	"image"
+	"log"
+)
+
+// relativeValue gets a dimension in pixels, from either a number or a percentage of the parent
+func relativeValue(value interface{}, parent int, defaultValue int) int {
+	switch cast := value.(type) {
+	case float64:
+		return int(cast)
+	case string:
+		return int(helper.GetRelativeDimension(parent, cast))
+	}
+	return defaultValue
+}
+
+// subFrame copies part of a frame into a new image starting at 0,0
+func subFrame(frame image.Image, rect image.Rectangle) *image.Image {
+	output := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Copy(output, image.Point{}, frame, rect, draw.Src, nil)
+	genericImage := image.Image(output)
+	return &genericImage
+}
+
+// cropFrames crops every frame to the same rectangle
+func cropFrames(frames []*image.Image, crop *entity.Crop) []*image.Image {
+	output := make([]*image.Image, len(frames))
+	for i, frame := range frames {
+		bounds := (*frame).Bounds()
+		x := relativeValue(crop.X, bounds.Dx(), 0)
+		y := relativeValue(crop.Y, bounds.Dy(), 0)
+		rect := image.Rect(x, y, x+relativeValue(crop.Width, bounds.Dx(), bounds.Dx()-x), y+relativeValue(crop.Height, bounds.Dy(), bounds.Dy()-y))
+		rect = rect.Add(bounds.Min).Intersect(bounds)
+		if rect.Empty() {
+			log.Println("Crop is outside of the image", rect)
+			return frames
+		}
+		output[i] = subFrame(*frame, rect)
+	}
+	return output
+}
+
+// sliceSprite cuts the first frame of a sprite sheet into a grid of tiles, which become the component's frames.
+// Tiles are shown for the default delay if the sprite doesn't have one
+func sliceSprite(frames []*image.Image, delays []int, sprite *entity.Sprite, defaultDelay int) ([]*image.Image, []int) {
+	sheet := *frames[0]
+	bounds := sheet.Bounds()
+	columns := sprite.Columns
+	rows := sprite.Rows
+	if columns < 1 {
+		columns = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	tileWidth := bounds.Dx() / columns
+	tileHeight := bounds.Dy() / rows
+	if tileWidth < 1 || tileHeight < 1 {
+		log.Println("Sprite grid is bigger than the image", columns, rows)
+		return frames, delays
+	}
+
+	count := columns * rows
+	if sprite.Count > 0 && sprite.Count < count {
+		count = sprite.Count
+	}
+
+	tiles := sprite.Frames
+	if len(tiles) == 0 {
+		tiles = make([]int, count)
+		for i := range tiles {
+			tiles[i] = i
+		}
+	}
+
+	delay := sprite.Delay
+	if delay == 0 {
+		delay = defaultDelay
+	}
+
+	outputImages := make([]*image.Image, 0, len(tiles))
+	outputDelay := make([]int, 0, len(tiles))
+	for _, tile := range tiles {
+		if tile < 0 || tile >= count {
+			log.Println("Sprite tile out of range", tile)
+			continue
+		}
+		// Tiles are numbered left to right, top to bottom
+		origin := bounds.Min.Add(image.Pt((tile%columns)*tileWidth, (tile/columns)*tileHeight))
+		outputImages = append(outputImages, subFrame(sheet, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(tileWidth, tileHeight))}))
+		outputDelay = append(outputDelay, delay)
+	}
+
+	if len(outputImages) == 0 {
+		return frames, delays
+	}
+	// A single tile is a still image, which shouldn't get a delay
+	if len(outputImages) == 1 {
+		return outputImages, []int{}
+	}
+	return outputImages, outputDelay
+}
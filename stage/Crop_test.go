@@ -0,0 +1,51 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+// spriteSheet makes a 4x2 grid of 10x10 tiles, where each tile's red channel is its index
+func spriteSheet() []*image.Image {
+	sheet := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			sheet.Pix[sheet.PixOffset(x, y)] = uint8((y/10)*4 + x/10)
+			sheet.Pix[sheet.PixOffset(x, y)+3] = 255
+		}
+	}
+	genericImage := image.Image(sheet)
+	return []*image.Image{&genericImage}
+}
+
+func TestCropFrames(t *testing.T) {
+	frames := cropFrames(spriteSheet(), &entity.Crop{X: "50%", Y: float64(10), Width: float64(10)})
+	assert.Equal(t, image.Rect(0, 0, 10, 10), (*frames[0]).Bounds())
+	assert.Equal(t, uint8(6), (*frames[0]).(*image.RGBA).Pix[0])
+}
+
+func TestSliceSprite(t *testing.T) {
+	frames, delays := sliceSprite(spriteSheet(), []int{}, &entity.Sprite{Columns: 4, Rows: 2, Count: 7, Delay: 5}, 10)
+	assert.Len(t, frames, 7)
+	assert.Equal(t, []int{5, 5, 5, 5, 5, 5, 5}, delays)
+	for i, frame := range frames {
+		assert.Equal(t, image.Rect(0, 0, 10, 10), (*frame).Bounds())
+		assert.Equal(t, uint8(i), (*frame).(*image.RGBA).Pix[0])
+	}
+
+	frames, delays = sliceSprite(spriteSheet(), []int{}, &entity.Sprite{Columns: 4, Rows: 2, Frames: []int{5}}, 10)
+	assert.Len(t, frames, 1)
+	assert.Empty(t, delays)
+	assert.Equal(t, uint8(5), (*frames[0]).(*image.RGBA).Pix[0])
+
+	// Tiles can't be smaller than a pixel
+	sheet := spriteSheet()
+	frames, _ = sliceSprite(sheet, []int{}, &entity.Sprite{Columns: 50, Rows: 2}, 10)
+	assert.Equal(t, sheet, frames)
+
+	// Without a delay the tiles get the default one
+	_, delays = sliceSprite(spriteSheet(), []int{}, &entity.Sprite{Columns: 4, Rows: 2, Frames: []int{0, 1}}, 4)
+	assert.Equal(t, []int{4, 4}, delays)
+}
@@ -0,0 +1,102 @@
+package stage
+
+import (
+	"golang.org/x/image/draw"
+	"image"
+	"log"
+	"math"
+	"strings"
+)
+
+// anchorPoint turns an anchor such as "top-left", "bottom" or "center" into how far along each axis
+// the image is aligned, from 0 (left/top) to 1 (right/bottom)
+func anchorPoint(anchor string) (float64, float64) {
+	x, y := 0.5, 0.5
+	anchor = strings.ToLower(anchor)
+	if strings.Contains(anchor, "left") {
+		x = 0
+	} else if strings.Contains(anchor, "right") {
+		x = 1
+	}
+	if strings.Contains(anchor, "top") {
+		y = 0
+	} else if strings.Contains(anchor, "bottom") {
+		y = 1
+	}
+	return x, y
+}
+
+// fitRects works out which part of the source is drawn to which part of a width x height slot:
+//   - fill: the whole source is stretched to the slot
+//   - contain: the whole source is scaled to fit inside the slot, keeping its aspect ratio
+//   - cover: the source is scaled to cover the slot, keeping its aspect ratio and cropping the rest
+//   - none: the source is drawn at its own size and cropped to the slot
+//   - scale-down: the same as none, unless the source is too big, then the same as contain
+func fitRects(source image.Rectangle, width int, height int, fit string, anchor string) (image.Rectangle, image.Rectangle) {
+	slot := image.Rect(0, 0, width, height)
+	sourceWidth := float64(source.Dx())
+	sourceHeight := float64(source.Dy())
+	if sourceWidth == 0 || sourceHeight == 0 {
+		return source, slot
+	}
+
+	var scale float64
+	switch fit {
+	case "", "fill":
+		return source, slot
+	case "contain":
+		scale = math.Min(float64(width)/sourceWidth, float64(height)/sourceHeight)
+	case "cover":
+		scale = math.Max(float64(width)/sourceWidth, float64(height)/sourceHeight)
+	case "none":
+		scale = 1
+	case "scale-down":
+		scale = math.Min(1, math.Min(float64(width)/sourceWidth, float64(height)/sourceHeight))
+	default:
+		log.Println("Unknown fit mode", fit)
+		return source, slot
+	}
+
+	anchorX, anchorY := anchorPoint(anchor)
+	scaledWidth := sourceWidth * scale
+	scaledHeight := sourceHeight * scale
+	// Where the scaled source sits relative to the slot, which is negative if it overflows
+	offsetX := (float64(width) - scaledWidth) * anchorX
+	offsetY := (float64(height) - scaledHeight) * anchorY
+
+	placed := image.Rect(
+		int(math.Round(offsetX)),
+		int(math.Round(offsetY)),
+		int(math.Round(offsetX+scaledWidth)),
+		int(math.Round(offsetY+scaledHeight)),
+	)
+	target := placed.Intersect(slot)
+	if target.Empty() {
+		return image.Rectangle{}, image.Rectangle{}
+	}
+
+	// Map the visible part of the placed image back onto the source to crop it
+	sourceRect := image.Rect(
+		source.Min.X+int(math.Round(float64(target.Min.X-placed.Min.X)/scale)),
+		source.Min.Y+int(math.Round(float64(target.Min.Y-placed.Min.Y)/scale)),
+		source.Min.X+int(math.Round(float64(target.Max.X-placed.Min.X)/scale)),
+		source.Min.Y+int(math.Round(float64(target.Max.Y-placed.Min.Y)/scale)),
+	).Intersect(source)
+	return sourceRect, target
+}
+
+// fitFrame resizes a frame into a width x height slot according to the fit mode and anchor, using the resample mode
+func fitFrame(frame image.Image, width int, height int, fit string, anchor string, resample string) *image.RGBA {
+	output := image.NewRGBA(image.Rect(0, 0, width, height))
+	sourceRect, targetRect := fitRects(frame.Bounds(), width, height, fit, anchor)
+	if targetRect.Empty() || sourceRect.Empty() {
+		return output
+	}
+
+	if sourceRect.Dx() == targetRect.Dx() && sourceRect.Dy() == targetRect.Dy() {
+		draw.Copy(output, targetRect.Min, frame, sourceRect, draw.Src, nil)
+	} else {
+		getResampler(resample, sourceRect, targetRect).Scale(output, targetRect, frame, sourceRect, draw.Src, nil)
+	}
+	return output
+}
@@ -0,0 +1,35 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image"
+	"testing"
+)
+
+func TestFitRects(t *testing.T) {
+	source := image.Rect(0, 0, 200, 100)
+	cases := []struct {
+		fit    string
+		anchor string
+		source image.Rectangle
+		target image.Rectangle
+	}{
+		{"fill", "", source, image.Rect(0, 0, 100, 100)},
+		{"contain", "", source, image.Rect(0, 25, 100, 75)},
+		{"contain", "top", source, image.Rect(0, 0, 100, 50)},
+		{"cover", "", image.Rect(50, 0, 150, 100), image.Rect(0, 0, 100, 100)},
+		{"cover", "left", image.Rect(0, 0, 100, 100), image.Rect(0, 0, 100, 100)},
+		{"none", "bottom-right", image.Rect(100, 0, 200, 100), image.Rect(0, 0, 100, 100)},
+		{"scale-down", "", source, image.Rect(0, 25, 100, 75)},
+	}
+	for _, c := range cases {
+		sourceRect, targetRect := fitRects(source, 100, 100, c.fit, c.anchor)
+		assert.Equal(t, c.source, sourceRect, "%s %s source", c.fit, c.anchor)
+		assert.Equal(t, c.target, targetRect, "%s %s target", c.fit, c.anchor)
+	}
+
+	// A source smaller than the slot isn't scaled up by scale-down
+	sourceRect, targetRect := fitRects(image.Rect(0, 0, 20, 10), 100, 100, "scale-down", "")
+	assert.Equal(t, image.Rect(0, 0, 20, 10), sourceRect)
+	assert.Equal(t, image.Rect(40, 45, 60, 55), targetRect)
+}
@@ -0,0 +1,74 @@
+package stage
+
+import (
+	"fmt"
+	"github.com/fogleman/gg"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	white       = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red         = color.RGBA{R: 255, A: 255}
+	green       = color.RGBA{G: 255, A: 255}
+	blue        = color.RGBA{B: 255, A: 255}
+	transparent = color.RGBA{}
+)
+
+func assertPixel(t *testing.T, frame *image.Image, x int, y int, expected color.RGBA) {
+	assert.Equal(t, expected, color.RGBAModel.Convert((*frame).At(x, y)), "pixel at %d,%d", x, y)
+}
+
+func squareSVG(size int, colour string) string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d"><rect width="%d" height="%d" fill="%s"/></svg>`, size, size, size, size, colour)
+}
+
+func square(x float64, y float64, size int, colour string) *entity.ImageComponent {
+	return &entity.ImageComponent{
+		URL:      squareSVG(size, colour),
+		Position: entity.Position{X: x, Y: y, Width: float64(size), Height: float64(size)},
+	}
+}
+
+func renderFrame(t *testing.T, request *entity.ImageRequest) *image.Image {
+	outputContexts, _, _, err := Render(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := outputContexts[0].Image()
+	return &frame
+}
+
+// solidFrame makes a frame of a single colour
+func solidFrame(width int, height int, colour color.RGBA) *image.RGBA {
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(frame, frame.Rect, image.NewUniform(colour), image.Point{}, draw.Src)
+	return frame
+}
+
+// colourContext makes a small output frame of a single colour
+func colourContext(colour color.RGBA) *gg.Context {
+	return gg.NewContextForRGBA(solidFrame(4, 4, colour))
+}
+
+// blankFrames makes count transparent frames, for tests that only need the number of frames
+func blankFrames(count int) []*image.Image {
+	frames := make([]*image.Image, count)
+	for i := range frames {
+		frame := image.Image(solidFrame(1, 1, transparent))
+		frames[i] = &frame
+	}
+	return frames
+}
+
+func repeatDelay(delay int, count int) []int {
+	delays := make([]int, count)
+	for i := range delays {
+		delays[i] = delay
+	}
+	return delays
+}
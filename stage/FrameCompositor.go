@@ -0,0 +1,97 @@
+package stage
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// frameCompositor holds the logical screen of an animated image and stacks each decoded sub-frame onto it,
+// keeping track of what needs to be restored once the frame has been displayed
+type frameCompositor struct {
+	canvas *image.RGBA
+	// The area (and its contents) that was covered by the last frame, restored for DisposalPrevious
+	saved     *image.RGBA
+	savedRect image.Rectangle
+}
+
+// checkCanvasSize rejects animation canvases larger than SVGs are rasterised at, as every frame is composited at that size
+func checkCanvasSize(format string, width int, height int) error {
+	if width < 1 || height < 1 || width > _maxSVGSize || height > _maxSVGSize {
+		return fmt.Errorf("%s: canvas size %dx%d is outside the %dx%d limit", format, width, height, _maxSVGSize, _maxSVGSize)
+	}
+	return nil
+}
+
+func newFrameCompositor(width int, height int) *frameCompositor {
+	return &frameCompositor{
+		canvas: image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+}
+
+// save takes a copy of the area that the next frame will draw over
+func (c *frameCompositor) save(rect image.Rectangle) {
+	c.savedRect = rect.Intersect(c.canvas.Rect)
+	c.saved = image.NewRGBA(c.savedRect)
+	draw.Draw(c.saved, c.savedRect, c.canvas, c.savedRect.Min, draw.Src)
+}
+
+// restore puts back the area taken with save
+func (c *frameCompositor) restore() {
+	if c.saved == nil {
+		return
+	}
+	draw.Draw(c.canvas, c.savedRect, c.saved, c.savedRect.Min, draw.Src)
+	c.saved = nil
+}
+
+// clear fills an area of the canvas with a colour, which is usually the background or transparent
+func (c *frameCompositor) clear(rect image.Rectangle, colour color.Color) {
+	draw.Draw(c.canvas, rect.Intersect(c.canvas.Rect), image.NewUniform(colour), image.Point{}, draw.Src)
+}
+
+// drawPaletted draws a paletted frame at its own bounds, skipping any transparent pixels
+func (c *frameCompositor) drawPaletted(frame *image.Paletted) {
+	rect := frame.Rect.Intersect(c.canvas.Rect)
+	// Converting each palette entry once is much cheaper than doing it per pixel
+	palette := make([]color.RGBA, len(frame.Palette))
+	for i, colour := range frame.Palette {
+		palette[i] = color.RGBAModel.Convert(colour).(color.RGBA)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		srcOffset := frame.PixOffset(rect.Min.X, y)
+		dstOffset := c.canvas.PixOffset(rect.Min.X, y)
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			index := int(frame.Pix[srcOffset])
+			srcOffset++
+			// Out of range indices are treated as transparent, the same way browsers do
+			if index < len(palette) && palette[index].A != 0 {
+				colour := palette[index]
+				c.canvas.Pix[dstOffset] = colour.R
+				c.canvas.Pix[dstOffset+1] = colour.G
+				c.canvas.Pix[dstOffset+2] = colour.B
+				c.canvas.Pix[dstOffset+3] = colour.A
+			}
+			dstOffset += 4
+		}
+	}
+}
+
+// drawImage draws a frame at the given bounds, either alpha blending over the canvas or replacing it
+func (c *frameCompositor) drawImage(frame image.Image, rect image.Rectangle, blend bool) {
+	op := draw.Src
+	if blend {
+		op = draw.Over
+	}
+	target := rect.Intersect(c.canvas.Rect)
+	draw.Draw(c.canvas, target, frame, frame.Bounds().Min.Add(target.Min.Sub(rect.Min)), op)
+}
+
+// snapshot returns a copy of the current state of the canvas
+func (c *frameCompositor) snapshot() *image.Image {
+	clone := image.NewRGBA(c.canvas.Rect)
+	copy(clone.Pix, c.canvas.Pix)
+	genericImage := image.Image(clone)
+	return &genericImage
+}
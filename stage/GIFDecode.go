@@ -0,0 +1,85 @@
+package stage
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"log"
+)
+
+// decodeGIF decodes every frame of a GIF and composites them onto the logical screen,
+// honouring frame offsets, transparency and the disposal method of each frame
+func decodeGIF(reader io.Reader) ([]*image.Image, []int, error) {
+	log.Println("Decoding the gif...")
+	gifFile, err := gif.DecodeAll(reader)
+	if err != nil {
+		log.Printf("Error decoding gif: %s\n", err)
+		return nil, nil, err
+	}
+
+	log.Println("Stacking frames...")
+	screen := gifScreenBounds(gifFile)
+	compositor := newFrameCompositor(screen.Dx(), screen.Dy())
+	background := gifBackground(gifFile)
+
+	output := make([]*image.Image, len(gifFile.Image))
+	for i, frame := range gifFile.Image {
+		disposal := gifFile.Disposal[i]
+
+		// DisposalPrevious needs whatever was under this frame before it was drawn
+		if disposal == gif.DisposalPrevious {
+			compositor.save(frame.Bounds())
+		}
+
+		compositor.drawPaletted(frame)
+		output[i] = compositor.snapshot()
+
+		// The disposal method describes what happens to the frame area after it has been displayed
+		//  - DisposalNone (or unspecified): the frame is left in place for the next one to draw over
+		//  - DisposalBackground: the frame area is cleared to the background colour
+		//  - DisposalPrevious: the frame area is restored to how it was before this frame was drawn
+		switch disposal {
+		case gif.DisposalBackground:
+			compositor.clear(frame.Bounds(), frameBackground(frame, gifFile.BackgroundIndex, background))
+		case gif.DisposalPrevious:
+			compositor.restore()
+		}
+	}
+
+	return output, gifFile.Delay, nil
+}
+
+// gifScreenBounds gets the logical screen size, growing it to fit any frames that are larger than it claims
+func gifScreenBounds(gifFile *gif.GIF) image.Rectangle {
+	screen := image.Rect(0, 0, gifFile.Config.Width, gifFile.Config.Height)
+	for _, frame := range gifFile.Image {
+		if frame.Rect.Max.X > screen.Max.X {
+			screen.Max.X = frame.Rect.Max.X
+		}
+		if frame.Rect.Max.Y > screen.Max.Y {
+			screen.Max.Y = frame.Rect.Max.Y
+		}
+	}
+	return screen
+}
+
+// gifBackground gets the background colour from the global colour table, or transparent if there isn't one
+func gifBackground(gifFile *gif.GIF) color.Color {
+	palette, ok := gifFile.Config.ColorModel.(color.Palette)
+	if !ok || int(gifFile.BackgroundIndex) >= len(palette) {
+		return color.Transparent
+	}
+	return palette[gifFile.BackgroundIndex]
+}
+
+// frameBackground gets the colour a frame is cleared to. A frame that uses the background index as its
+// transparent colour is cleared to transparent, as that's what the encoder intended
+func frameBackground(frame *image.Paletted, backgroundIndex byte, background color.Color) color.Color {
+	if int(backgroundIndex) < len(frame.Palette) {
+		if _, _, _, a := frame.Palette[backgroundIndex].RGBA(); a == 0 {
+			return color.Transparent
+		}
+	}
+	return background
+}
@@ -0,0 +1,67 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"os"
+	"path"
+	"testing"
+)
+
+func decodeFixture(t *testing.T, name string) []*image.Image {
+	file, err := os.Open(path.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	frames, delays, err := getImage(file, &entity.ImageComponent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(frames), len(delays))
+	return frames
+}
+
+func TestDecodeGIFOffset(t *testing.T) {
+	frames := decodeFixture(t, "offset.gif")
+	assertPixel(t, frames[1], 0, 0, red)
+	assertPixel(t, frames[1], 1, 1, red)
+	assertPixel(t, frames[1], 2, 2, blue)
+	assertPixel(t, frames[1], 3, 3, blue)
+}
+
+func TestDecodeGIFLogicalScreen(t *testing.T) {
+	frames := decodeFixture(t, "screen.gif")
+	for _, frame := range frames {
+		assert.Equal(t, image.Rect(0, 0, 4, 4), (*frame).Bounds())
+	}
+	assertPixel(t, frames[0], 0, 0, transparent)
+	assertPixel(t, frames[0], 1, 1, red)
+	assertPixel(t, frames[0], 3, 3, transparent)
+	assertPixel(t, frames[1], 0, 0, green)
+	assertPixel(t, frames[1], 2, 2, red)
+}
+
+func TestDecodeGIFDisposalBackground(t *testing.T) {
+	frames := decodeFixture(t, "disposal-background.gif")
+	assertPixel(t, frames[0], 2, 2, red)
+	assertPixel(t, frames[1], 0, 0, green)
+	assertPixel(t, frames[1], 2, 2, white)
+}
+
+func TestDecodeGIFDisposalPrevious(t *testing.T) {
+	frames := decodeFixture(t, "disposal-previous.gif")
+	assertPixel(t, frames[1], 0, 0, blue)
+	assertPixel(t, frames[1], 3, 3, red)
+	assertPixel(t, frames[2], 0, 0, red)
+	assertPixel(t, frames[2], 1, 1, red)
+	assertPixel(t, frames[2], 3, 3, green)
+}
+
+func TestDecodeGIFTransparentIndex(t *testing.T) {
+	frames := decodeFixture(t, "transparent.gif")
+	assertPixel(t, frames[1], 0, 0, green)
+	assertPixel(t, frames[1], 1, 0, red)
+	assertPixel(t, frames[1], 3, 3, red)
+}
@@ -3,27 +3,24 @@ package stage
 import (
 	"github.com/fogleman/gg"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
-	"golang.org/x/image/draw"
 	"image"
 	"sync"
 )
 
-// The fully intact previous frame, used to determine what has changed in the next frame
-var unmaskedPrevious image.Image
-
-func GIFOptimise(outputCtx *gg.Context, frameNum int, wg *sync.WaitGroup) {
-	// The image must be copied here, to avoid using the mask as the unmasked previous frame
-	maskCopy := image.NewRGBA(outputCtx.Image().Bounds())
-	draw.Copy(maskCopy, image.Point{X: 0, Y: 0}, outputCtx.Image(), outputCtx.Image().Bounds(), draw.Src, nil)
-	if frameNum > 0 {
-		wg.Add(1)
-		go diffMaskRGBA(maskCopy, unmaskedPrevious.(*image.RGBA), wg)
-		//go diffMask(outputCtx, unmaskedPrevious, wg, frameNum)
+// GIFOptimise erases the pixels of each frame that are the same as the frame before, so they can be encoded as
+// transparent. Frames are compared starting from the end so that each one is diffed against an intact previous frame
+func GIFOptimise(outputContexts []*gg.Context) {
+	for frameNum := len(outputContexts) - 1; frameNum > 0; frameNum-- {
+		current, ok := outputContexts[frameNum].Image().(*image.RGBA)
+		previous, previousOk := outputContexts[frameNum-1].Image().(*image.RGBA)
+		if !ok || !previousOk {
+			continue
+		}
+		diffMaskRGBA(current, previous, nil)
 	}
-	unmaskedPrevious = maskCopy
 }
 
-// Erases pixels on `context` that are different to those on `image2`
+// Erases pixels on `context` that are the same as those on `image2`
 func diffMask(context *gg.Context, image2 image.Image, wg *sync.WaitGroup, num int) {
 	if wg != nil {
 		defer wg.Done()
@@ -42,14 +39,15 @@ func diffMask(context *gg.Context, image2 image.Image, wg *sync.WaitGroup, num i
 	}
 }
 
+// Erases pixels on `image1` that are the same as those on `image2`, so the previous frame shows through them
 func diffMaskRGBA(image1 *image.RGBA, image2 *image.RGBA, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
 	}
-	imgLength := len(image1.Pix) - 4
+	imgLength := len(image1.Pix)
 
 	for i := 0; i < imgLength; i += 4 {
-		if image1.Pix[i] != image2.Pix[i] || image1.Pix[i+1] != image2.Pix[i+1] || image1.Pix[i+2] != image2.Pix[i+2] || image1.Pix[i+3] != image2.Pix[i+3] {
+		if image1.Pix[i] == image2.Pix[i] && image1.Pix[i+1] == image2.Pix[i+1] && image1.Pix[i+2] == image2.Pix[i+2] && image1.Pix[i+3] == image2.Pix[i+3] {
 			image1.Pix[i] = 0x00
 			image1.Pix[i+1] = 0x00
 			image1.Pix[i+2] = 0x00
@@ -2,6 +2,10 @@ package stage
 
 import (
 	"github.com/fogleman/gg"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"golang.org/x/image/draw"
 	"image"
 	"testing"
 )
@@ -44,3 +48,53 @@ func BenchmarkDiffMaskRGBA(b *testing.B) {
 		diffMaskRGBA(blackWhiteImage, whiteBlackImage, nil)
 	}
 }
+
+func TestGIFOptimise(t *testing.T) {
+	first := colourContext(blue)
+	second := colourContext(blue)
+	second.SetColor(red)
+	second.DrawRectangle(0, 0, 2, 2)
+	second.Fill()
+
+	GIFOptimise([]*gg.Context{first, second})
+
+	// Only the changed pixels are left, including the last one
+	diffed := second.Image().(*image.RGBA)
+	assert.Equal(t, transparent, diffed.RGBAAt(3, 3))
+	assert.Equal(t, red, diffed.RGBAAt(0, 0))
+	assert.Equal(t, blue, first.Image().(*image.RGBA).RGBAAt(0, 0))
+}
+
+func TestGIFOptimiseTemplate(t *testing.T) {
+	if err := helper.LoadResources("../res"); err != nil {
+		t.Fatal(err)
+	}
+	template, _, err := getLocalImage(&entity.ImageComponent{URL: "epic.png", Local: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A square moving across the template, as an animated overlay on a template would be rendered
+	outputContexts := make([]*gg.Context, 4)
+	before := make([][]uint8, len(outputContexts))
+	for i := range outputContexts {
+		outputContexts[i] = gg.NewContextForImage(*template[0])
+		outputContexts[i].SetColor(red)
+		outputContexts[i].DrawRectangle(float64(i*20), 20, 40, 40)
+		outputContexts[i].Fill()
+		before[i] = append([]uint8{}, outputContexts[i].Image().(*image.RGBA).Pix...)
+	}
+
+	GIFOptimise(outputContexts)
+
+	// Drawn over each other as a GIF with no disposal would be, each frame comes back as it was
+	bounds := outputContexts[0].Image().Bounds()
+	composited := image.NewRGBA(bounds)
+	for i, ctx := range outputContexts {
+		draw.Draw(composited, bounds, ctx.Image(), bounds.Min, draw.Over)
+		assert.Equal(t, before[i], composited.Pix, "frame %d", i)
+	}
+	// Everything away from the square is left for the previous frame to show
+	last := outputContexts[len(outputContexts)-1].Image().(*image.RGBA)
+	assert.Equal(t, transparent, last.RGBAAt(bounds.Dx()-1, bounds.Dy()-1))
+}
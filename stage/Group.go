@@ -0,0 +1,58 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"log"
+	"sort"
+)
+
+// sortComponents orders components by their z index, keeping the request order for components with the same z
+func sortComponents(components []*entity.ImageComponent) {
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Z < components[j].Z
+	})
+}
+
+// renderGroup renders the children of a group component onto their own canvas, so the group can then be
+// positioned, transformed, filtered and masked like any other image. Without a size the group is sized to fit
+func renderGroup(request *entity.ImageRequest, component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	if len(component.Children) == 0 {
+		log.Println("Group has no children")
+		return nil, nil, nil
+	}
+
+	groupRequest := &entity.ImageRequest{
+		ImageComponents: component.Children,
+		Width:           relativeValue(component.Position.Width, request.Width, 0),
+		Height:          relativeValue(component.Position.Height, request.Height, 0),
+		Debug:           request.Debug,
+		Version:         request.Version,
+		// The group is scaled as a whole once it's drawn, so its children shouldn't be scaled down beforehand
+		MaxWidth: -1,
+		Resample: request.Resample,
+		// Times in the children are measured the same as in the rest of the request
+		Delay:          request.Delay,
+		FPS:            request.FPS,
+		MaxFrames:      request.MaxFrames,
+		FrameTolerance: request.FrameTolerance,
+	}
+	groupRequest.Expand = groupRequest.Width == 0 || groupRequest.Height == 0
+
+	outputContexts, outputDelay, _, exception := Render(groupRequest)
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	frameImages := make([]*image.Image, len(outputContexts))
+	for i, ctx := range outputContexts {
+		frameImage := ctx.Image()
+		frameImages[i] = &frameImage
+	}
+
+	// A single frame is a still image, which shouldn't get a delay
+	if len(frameImages) == 1 {
+		return frameImages, []int{}, nil
+	}
+	return frameImages, outputDelay, nil
+}
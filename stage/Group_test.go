@@ -0,0 +1,73 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"testing"
+)
+
+// squareSVG is an inline image of a solid square
+func TestZOrder(t *testing.T) {
+	bottom := square(0, 0, 10, "#ffffff")
+	top := square(0, 0, 10, "#ff0000")
+	top.Z = 1
+	middle := square(0, 0, 10, "#0000ff")
+	request := &entity.ImageRequest{
+		ImageComponents: []*entity.ImageComponent{bottom, top, middle},
+		Width:           10,
+		Height:          10,
+	}
+	assertPixel(t, renderFrame(t, request), 5, 5, red)
+	// Components with the same z keep their order
+	assert.Equal(t, []*entity.ImageComponent{bottom, middle, top}, request.ImageComponents)
+}
+
+func TestGroupChildrenAreRelative(t *testing.T) {
+	group := &entity.ImageComponent{
+		Type:     "group",
+		Position: entity.Position{X: float64(10), Y: float64(10)},
+		Children: []*entity.ImageComponent{square(0, 0, 10, "#ff0000"), square(10, 0, 10, "#0000ff")},
+	}
+	frame := renderFrame(t, &entity.ImageRequest{
+		ImageComponents: []*entity.ImageComponent{square(0, 0, 40, "#ffffff"), group},
+	})
+	assertPixel(t, frame, 5, 5, white)
+	assertPixel(t, frame, 15, 15, red)
+	assertPixel(t, frame, 25, 15, blue)
+	assertPixel(t, frame, 35, 15, white)
+}
+
+func TestGroupOpacity(t *testing.T) {
+	opacity := 0.5
+	group := &entity.ImageComponent{
+		Type:     "group",
+		Opacity:  &opacity,
+		Children: []*entity.ImageComponent{square(0, 0, 10, "#ff0000"), square(0, 0, 10, "#0000ff")},
+	}
+	frame := renderFrame(t, &entity.ImageRequest{
+		ImageComponents: []*entity.ImageComponent{square(0, 0, 10, "#ffffff"), group},
+	})
+	// The children are composited first, so only the top one shows through rather than a mix of both
+	r, g, b, _ := (*frame).At(5, 5).RGBA()
+	assert.Equal(t, r>>8, g>>8)
+	assert.Less(t, r>>8, b>>8)
+}
+
+func TestGroupTiming(t *testing.T) {
+	child := square(0, 0, 10, "#ff0000")
+	child.Filters = []*entity.Filter{{Name: "animate", Arguments: map[string]interface{}{
+		"delay": float64(5),
+		"keyframes": []interface{}{
+			map[string]interface{}{"time": float64(0), "x": float64(0)},
+			map[string]interface{}{"time": float64(2), "x": float64(10)},
+		},
+	}}}
+	group := &entity.ImageComponent{Type: "group", Children: []*entity.ImageComponent{child}}
+	// 2 frames of the request's delay is 10cs, which the children should use too
+	frameImages, frameDelay, err := renderGroup(&entity.ImageRequest{Delay: 5}, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, frameImages, 3)
+	assert.Equal(t, []int{5, 5, 5}, frameDelay)
+}
@@ -0,0 +1,281 @@
+package stage
+
+import (
+	"errors"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"math"
+)
+
+// ErrUnsizedLayoutChild is returned when a layout can't tell how big a child is, so would draw it over the others
+var ErrUnsizedLayoutChild = errors.New("layout child has no size, give it one or set the layout's itemWidth and itemHeight")
+
+// layoutItem is a child being laid out, with its size along and across the line
+type layoutItem struct {
+	component *entity.ImageComponent
+	main      float64
+	cross     float64
+}
+
+// ResolveLayouts positions the children of every layout component, turning them into groups
+func ResolveLayouts(request *entity.ImageRequest) error {
+	for _, component := range request.ImageComponents {
+		if exception := resolveLayout(component, request.Width, request.Height); exception != nil {
+			return exception
+		}
+	}
+	return nil
+}
+
+// resolveLayout positions the children of a layout component and sizes it to fit them if it has no size of its own.
+// Nested layouts are resolved first so that their size is known
+func resolveLayout(component *entity.ImageComponent, parentWidth int, parentHeight int) error {
+	if component.Type != "layout" {
+		return nil
+	}
+	layout := component.Layout
+	if layout == nil {
+		layout = &entity.Layout{}
+	}
+
+	width := float64(relativeValue(component.Position.Width, parentWidth, 0))
+	height := float64(relativeValue(component.Position.Height, parentHeight, 0))
+	contentWidth := math.Max(0, width-layout.Padding*2)
+	contentHeight := math.Max(0, height-layout.Padding*2)
+
+	for _, child := range component.Children {
+		if exception := resolveLayout(child, int(contentWidth), int(contentHeight)); exception != nil {
+			return exception
+		}
+	}
+
+	var usedWidth, usedHeight float64
+	var exception error
+	switch layout.Direction {
+	case "grid":
+		usedWidth, usedHeight, exception = layoutGrid(component.Children, layout, contentWidth, contentHeight)
+	case "column":
+		usedHeight, usedWidth, exception = layoutLines(component.Children, layout, contentHeight, contentWidth, true)
+	default:
+		usedWidth, usedHeight, exception = layoutLines(component.Children, layout, contentWidth, contentHeight, false)
+	}
+	if exception != nil {
+		return exception
+	}
+
+	for _, child := range component.Children {
+		child.Position.X = child.Position.X.(float64) + layout.Padding
+		child.Position.Y = child.Position.Y.(float64) + layout.Padding
+	}
+
+	if width == 0 {
+		width = usedWidth + layout.Padding*2
+	}
+	if height == 0 {
+		height = usedHeight + layout.Padding*2
+	}
+	component.Position.Width = width
+	component.Position.Height = height
+	component.Type = "group"
+	return nil
+}
+
+// alignFactor gets how far along the free space an item goes for an alignment
+func alignFactor(align string) float64 {
+	switch align {
+	case "center":
+		return 0.5
+	case "end":
+		return 1
+	}
+	return 0
+}
+
+// childSize gets the size a child asked for, falling back to the layout's item size
+func childSize(child *entity.ImageComponent, layout *entity.Layout, contentWidth float64, contentHeight float64) (float64, float64) {
+	width := float64(relativeValue(child.Position.Width, int(contentWidth), 0))
+	height := float64(relativeValue(child.Position.Height, int(contentHeight), 0))
+	if width == 0 {
+		width = layout.ItemWidth
+	}
+	if height == 0 {
+		height = layout.ItemHeight
+	}
+	return width, height
+}
+
+// placeChild sets a child's position and size, leaving sizes that still aren't known for the image to decide
+func placeChild(child *entity.ImageComponent, x float64, y float64, width float64, height float64) {
+	child.Position.X = x
+	child.Position.Y = y
+	if width > 0 {
+		child.Position.Width = width
+	}
+	if height > 0 {
+		child.Position.Height = height
+	}
+}
+
+// layoutLines places children one after another along the main axis, wrapping onto new lines if enabled.
+// Main is the x axis for rows and y for columns. Returns the size used along and across the lines.
+// Children need a size along the line unless they can share the space left on a line that doesn't wrap
+func layoutLines(children []*entity.ImageComponent, layout *entity.Layout, mainSize float64, crossSize float64, column bool) (float64, float64, error) {
+	items := make([]*layoutItem, len(children))
+	unsized := 0
+	sizedMain := 0.0
+	for i, child := range children {
+		contentWidth, contentHeight := contentSize(mainSize, crossSize, column)
+		width, height := childSize(child, layout, contentWidth, contentHeight)
+		items[i] = &layoutItem{component: child, main: width, cross: height}
+		if column {
+			items[i].main, items[i].cross = height, width
+		}
+		if items[i].main == 0 {
+			unsized++
+		}
+		sizedMain += items[i].main
+	}
+
+	// Without wrapping, children with no size share whatever space is left on the line
+	if !layout.Wrap && mainSize > 0 && unsized > 0 {
+		share := math.Max(0, (mainSize-sizedMain-layout.Gap*float64(len(items)-1))/float64(unsized))
+		for _, item := range items {
+			if item.main == 0 {
+				item.main = share
+			}
+		}
+	} else if unsized > 0 {
+		return 0, 0, ErrUnsizedLayoutChild
+	}
+
+	lines := make([][]*layoutItem, 0)
+	line := make([]*layoutItem, 0)
+	lineMain := 0.0
+	for _, item := range items {
+		if layout.Wrap && mainSize > 0 && len(line) > 0 && lineMain+layout.Gap+item.main > mainSize {
+			lines = append(lines, line)
+			line = make([]*layoutItem, 0)
+			lineMain = 0
+		}
+		if len(line) > 0 {
+			lineMain += layout.Gap
+		}
+		lineMain += item.main
+		line = append(line, item)
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	// Lines are stacked by their tallest child, so wrapped lines of children without a cross size would overlap
+	if len(lines) > 1 {
+		for _, item := range items {
+			if item.cross == 0 {
+				return 0, 0, ErrUnsizedLayoutChild
+			}
+		}
+	}
+
+	usedMain := 0.0
+	crossPosition := 0.0
+	for l, line := range lines {
+		lineMain := layout.Gap * float64(len(line)-1)
+		lineCross := 0.0
+		for _, item := range line {
+			lineMain += item.main
+			lineCross = math.Max(lineCross, item.cross)
+		}
+		// A single line fills the layout
+		if len(lines) == 1 && crossSize > 0 {
+			lineCross = crossSize
+		}
+
+		free := 0.0
+		if mainSize > 0 {
+			free = math.Max(0, mainSize-lineMain)
+		}
+		mainPosition := free * alignFactor(layout.Justify)
+		spacing := layout.Gap
+		if layout.Justify == "space-between" && len(line) > 1 {
+			mainPosition = 0
+			spacing += free / float64(len(line)-1)
+		}
+
+		for _, item := range line {
+			if item.cross == 0 || layout.Align == "stretch" {
+				item.cross = lineCross
+			}
+			itemCross := crossPosition + (lineCross-item.cross)*alignFactor(layout.Align)
+			if column {
+				placeChild(item.component, itemCross, mainPosition, item.cross, item.main)
+			} else {
+				placeChild(item.component, mainPosition, itemCross, item.main, item.cross)
+			}
+			mainPosition += item.main + spacing
+		}
+
+		usedMain = math.Max(usedMain, lineMain)
+		crossPosition += lineCross
+		if l < len(lines)-1 {
+			crossPosition += layout.Gap
+		}
+	}
+	return usedMain, crossPosition, nil
+}
+
+// contentSize turns main and cross sizes back into a width and height
+func contentSize(mainSize float64, crossSize float64, column bool) (float64, float64) {
+	if column {
+		return crossSize, mainSize
+	}
+	return mainSize, crossSize
+}
+
+// layoutGrid places children into equal cells, left to right and top to bottom. The cell size comes from the
+// layout's item size, or by dividing up the layout, or else the biggest child. Returns the size used
+func layoutGrid(children []*entity.ImageComponent, layout *entity.Layout, contentWidth float64, contentHeight float64) (float64, float64, error) {
+	if len(children) == 0 {
+		return 0, 0, nil
+	}
+	columns := layout.Columns
+	if columns < 1 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(children)))))
+	}
+	rows := (len(children) + columns - 1) / columns
+
+	cellWidth := layout.ItemWidth
+	if cellWidth == 0 && contentWidth > 0 {
+		cellWidth = math.Max(0, (contentWidth-layout.Gap*float64(columns-1))/float64(columns))
+	}
+	cellHeight := layout.ItemHeight
+	if cellHeight == 0 && contentHeight > 0 {
+		cellHeight = math.Max(0, (contentHeight-layout.Gap*float64(rows-1))/float64(rows))
+	}
+	if cellWidth == 0 || cellHeight == 0 {
+		for _, child := range children {
+			width, height := childSize(child, layout, contentWidth, contentHeight)
+			if layout.ItemWidth == 0 && contentWidth == 0 {
+				cellWidth = math.Max(cellWidth, width)
+			}
+			if layout.ItemHeight == 0 && contentHeight == 0 {
+				cellHeight = math.Max(cellHeight, height)
+			}
+		}
+	}
+	if cellWidth == 0 || cellHeight == 0 {
+		return 0, 0, ErrUnsizedLayoutChild
+	}
+
+	for i, child := range children {
+		width, height := childSize(child, layout, cellWidth, cellHeight)
+		// Children without a size fill their cell
+		if width == 0 {
+			width = cellWidth
+		}
+		if height == 0 || layout.Align == "stretch" {
+			height = cellHeight
+		}
+		cellX := float64(i%columns) * (cellWidth + layout.Gap)
+		cellY := float64(i/columns) * (cellHeight + layout.Gap)
+		placeChild(child, cellX+(cellWidth-width)*alignFactor(layout.Justify), cellY+(cellHeight-height)*alignFactor(layout.Align), width, height)
+	}
+	return float64(columns)*cellWidth + layout.Gap*float64(columns-1), float64(rows)*cellHeight + layout.Gap*float64(rows-1), nil
+}
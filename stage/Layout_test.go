@@ -0,0 +1,119 @@
+package stage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"testing"
+)
+
+func sized(width float64, height float64) *entity.ImageComponent {
+	return &entity.ImageComponent{Position: entity.Position{Width: width, Height: height}}
+}
+
+func assertPosition(t *testing.T, component *entity.ImageComponent, x float64, y float64, width float64, height float64) {
+	assert.Equal(t, entity.Position{X: x, Y: y, Width: width, Height: height}, component.Position)
+}
+
+func TestRowLayoutSizesToFit(t *testing.T) {
+	children := []*entity.ImageComponent{sized(10, 20), sized(30, 10)}
+	layout := &entity.ImageComponent{Type: "layout", Layout: &entity.Layout{Gap: 5, Padding: 2, Align: "center"}, Children: children}
+	assert.Nil(t, resolveLayout(layout, 0, 0))
+
+	assert.Equal(t, "group", layout.Type)
+	assert.Equal(t, float64(49), layout.Position.Width)
+	assert.Equal(t, float64(24), layout.Position.Height)
+	assertPosition(t, children[0], 2, 2, 10, 20)
+	assertPosition(t, children[1], 17, 7, 30, 10)
+}
+
+func TestColumnLayoutSharesSpace(t *testing.T) {
+	children := []*entity.ImageComponent{sized(0, 0), sized(0, 20), sized(0, 0)}
+	layout := &entity.ImageComponent{
+		Type:     "layout",
+		Position: entity.Position{Width: float64(50), Height: float64(100)},
+		Layout:   &entity.Layout{Direction: "column", Gap: 10},
+		Children: children,
+	}
+	assert.Nil(t, resolveLayout(layout, 0, 0))
+
+	assertPosition(t, children[0], 0, 0, 50, 30)
+	assertPosition(t, children[1], 0, 40, 50, 20)
+	assertPosition(t, children[2], 0, 70, 50, 30)
+}
+
+func TestRowLayoutWraps(t *testing.T) {
+	children := []*entity.ImageComponent{sized(40, 10), sized(40, 20), sized(40, 10)}
+	layout := &entity.ImageComponent{
+		Type:     "layout",
+		Position: entity.Position{Width: float64(100)},
+		Layout:   &entity.Layout{Wrap: true, Gap: 10, Justify: "space-between"},
+		Children: children,
+	}
+	assert.Nil(t, resolveLayout(layout, 0, 0))
+
+	assertPosition(t, children[0], 0, 0, 40, 10)
+	assertPosition(t, children[1], 60, 0, 40, 20)
+	assertPosition(t, children[2], 0, 30, 40, 10)
+	assert.Equal(t, float64(40), layout.Position.Height)
+}
+
+func TestGridLayout(t *testing.T) {
+	children := []*entity.ImageComponent{sized(0, 0), sized(10, 10), sized(0, 0), sized(0, 0), sized(0, 0)}
+	layout := &entity.ImageComponent{
+		Type:     "layout",
+		Layout:   &entity.Layout{Direction: "grid", Columns: 2, Gap: 4, ItemWidth: 20, ItemHeight: 20, Justify: "center", Align: "end"},
+		Children: children,
+	}
+	assert.Nil(t, resolveLayout(layout, 0, 0))
+
+	assertPosition(t, children[0], 0, 0, 20, 20)
+	assertPosition(t, children[1], 29, 10, 10, 10)
+	assertPosition(t, children[4], 0, 48, 20, 20)
+	assert.Equal(t, float64(44), layout.Position.Width)
+	assert.Equal(t, float64(68), layout.Position.Height)
+}
+
+func TestLayoutUnsizedChildren(t *testing.T) {
+	cases := map[string]*entity.Layout{
+		// Without a size there's no space to share out
+		"auto sized row": {},
+		"wrapping row":   {Wrap: true},
+		"auto grid":      {Direction: "grid"},
+	}
+	for name, layout := range cases {
+		component := &entity.ImageComponent{Type: "layout", Layout: layout, Children: []*entity.ImageComponent{sized(0, 0), sized(0, 0)}}
+		if layout.Wrap {
+			component.Position.Width = float64(100)
+		}
+		assert.True(t, errors.Is(resolveLayout(component, 0, 0), ErrUnsizedLayoutChild), name)
+	}
+
+	// Wrapped lines need to know how tall each child is
+	wrapped := &entity.ImageComponent{
+		Type:     "layout",
+		Position: entity.Position{Width: float64(50)},
+		Layout:   &entity.Layout{Wrap: true},
+		Children: []*entity.ImageComponent{sized(40, 0), sized(40, 10)},
+	}
+	assert.True(t, errors.Is(resolveLayout(wrapped, 0, 0), ErrUnsizedLayoutChild))
+
+	// The item size fills in for children without one
+	itemSized := &entity.ImageComponent{Type: "layout", Layout: &entity.Layout{Wrap: true, ItemWidth: 10, ItemHeight: 10}, Children: []*entity.ImageComponent{sized(0, 0), sized(0, 0)}}
+	assert.Nil(t, resolveLayout(itemSized, 0, 0))
+	assertPosition(t, itemSized.Children[1], 10, 0, 10, 10)
+}
+
+func TestLayoutRenders(t *testing.T) {
+	layout := &entity.ImageComponent{
+		Type:     "layout",
+		Position: entity.Position{X: float64(5), Y: float64(5)},
+		Children: []*entity.ImageComponent{square(0, 0, 10, "#ff0000"), square(0, 0, 10, "#0000ff")},
+	}
+	frame := renderFrame(t, &entity.ImageRequest{
+		ImageComponents: []*entity.ImageComponent{square(0, 0, 30, "#ffffff"), layout},
+	})
+	assertPixel(t, frame, 10, 10, red)
+	assertPixel(t, frame, 20, 10, blue)
+	assertPixel(t, frame, 27, 10, white)
+}
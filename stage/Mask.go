@@ -0,0 +1,109 @@
+package stage
+
+import (
+	"github.com/fogleman/gg"
+	"github.com/getsentry/sentry-go"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"golang.org/x/image/draw"
+	"image"
+	"log"
+)
+
+// componentOpacity gets the opacity of a component, clamped between 0 and 1
+func componentOpacity(component *entity.ImageComponent) float64 {
+	if component.Opacity == nil || *component.Opacity > 1 {
+		return 1
+	}
+	if *component.Opacity < 0 {
+		return 0
+	}
+	return *component.Opacity
+}
+
+// maskAnimation is the decoded frames of a component's mask image, kept while the request renders
+type maskAnimation struct {
+	frames []*image.Image
+	delays []int
+}
+
+// loadMasks decodes the mask image of every component that has one. Shape masks are drawn as they're applied instead
+func loadMasks(request *entity.ImageRequest) ([]*maskAnimation, error) {
+	masks := make([]*maskAnimation, len(request.ImageComponents))
+	for comp, component := range request.ImageComponents {
+		// Masks can also apply to components without an image, such as a plain background
+		if component.Mask == nil || component.Mask.URL == "" {
+			continue
+		}
+		maskFrames, maskDelays, exception := getComponentImage(&entity.ImageComponent{URL: component.Mask.URL, Local: component.Mask.Local})
+		if exception != nil {
+			log.Println("Unable to get mask image:", exception)
+			sentry.CaptureException(exception)
+			return nil, exception
+		}
+		if len(maskFrames) > 0 {
+			masks[comp] = &maskAnimation{frames: maskFrames, delays: maskDelays}
+		}
+	}
+	return masks, nil
+}
+
+// applyMask multiplies each pixel of the resized frame by the component's mask and opacity.
+// The mask frame is the frame of the mask image shown at the time, or nil for shape masks
+func applyMask(frameImage *image.RGBA, component *entity.ImageComponent, maskFrame *image.Image) {
+	opacity := componentOpacity(component)
+	if component.Mask == nil && opacity == 1 {
+		return
+	}
+
+	var maskImage *image.RGBA
+	if component.Mask != nil {
+		maskImage = getMaskImage(component.Mask, frameImage.Rect.Dx(), frameImage.Rect.Dy(), maskFrame)
+	}
+
+	for y := 0; y < frameImage.Rect.Dy(); y++ {
+		offset := frameImage.PixOffset(frameImage.Rect.Min.X, frameImage.Rect.Min.Y+y)
+		for x := 0; x < frameImage.Rect.Dx(); x++ {
+			alpha := opacity
+			if maskImage != nil {
+				alpha *= float64(maskImage.Pix[maskImage.PixOffset(x, y)+3]) / 255
+			}
+			// The frame is premultiplied, so every channel is scaled rather than just the alpha
+			for c := 0; c < 4; c++ {
+				frameImage.Pix[offset+c] = uint8(float64(frameImage.Pix[offset+c]) * alpha)
+			}
+			offset += 4
+		}
+	}
+}
+
+// getMaskImage renders the mask at the size of the frame, only the alpha channel of the result is used
+func getMaskImage(mask *entity.Mask, width int, height int, maskFrame *image.Image) *image.RGBA {
+	if maskFrame != nil {
+		maskImage := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.BiLinear.Scale(maskImage, maskImage.Bounds(), *maskFrame, (*maskFrame).Bounds(), draw.Src, nil)
+		return maskImage
+	}
+
+	maskCtx := gg.NewContext(width, height)
+	maskCtx.SetRGB(1, 1, 1)
+	switch mask.Shape {
+	case "circle":
+		// Circles are stretched to the component, so a non-square component gets an ellipse
+		maskCtx.DrawEllipse(float64(width)/2, float64(height)/2, float64(width)/2, float64(height)/2)
+	case "roundrect":
+		maskCtx.DrawRoundedRectangle(0, 0, float64(width), float64(height), mask.Radius)
+	case "polygon":
+		for _, point := range mask.Points {
+			if len(point) < 2 {
+				continue
+			}
+			maskCtx.LineTo(point[0], point[1])
+		}
+		maskCtx.ClosePath()
+	default:
+		log.Println("Unknown mask shape", mask.Shape)
+		maskCtx.DrawRectangle(0, 0, float64(width), float64(height))
+	}
+	maskCtx.Fill()
+	return maskCtx.Image().(*image.RGBA)
+}
@@ -0,0 +1,51 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+func TestCircleMask(t *testing.T) {
+	frame := solidFrame(20, 20, red)
+	applyMask(frame, &entity.ImageComponent{Mask: &entity.Mask{Shape: "circle"}}, nil)
+	assert.Equal(t, transparent, frame.RGBAAt(0, 0))
+	assert.Equal(t, red, frame.RGBAAt(10, 10))
+}
+
+func TestOpacity(t *testing.T) {
+	frame := solidFrame(2, 2, red)
+	opacity := 0.5
+	applyMask(frame, &entity.ImageComponent{Opacity: &opacity}, nil)
+	assert.Equal(t, uint8(127), frame.RGBAAt(1, 1).A)
+	assert.Equal(t, uint8(127), frame.RGBAAt(1, 1).R)
+}
+
+func TestAnimatedImageMask(t *testing.T) {
+	full := image.Image(solidFrame(4, 4, red))
+	empty := image.Image(image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	mask := &maskAnimation{frames: []*image.Image{&full, &empty}, delays: []int{20, 20}}
+
+	// The component changes frame twice as often as the mask, which should keep its own timing
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{{Mask: &entity.Mask{URL: "mask.gif"}}}}
+	output := buildTimeline(request, [][]int{{10, 10}}, [][]*image.Image{blankFrames(2)}, []*maskAnimation{mask})
+	assert.Equal(t, []int{0, 10, 20, 30}, output.times)
+
+	for outputFrame, expected := range []uint8{255, 255, 0, 0} {
+		frame := solidFrame(8, 8, red)
+		applyMask(frame, request.ImageComponents[0], mask.frames[output.maskAt(0, outputFrame)])
+		assert.Equal(t, expected, frame.RGBAAt(4, 4).A, "frame %d", outputFrame)
+	}
+}
+
+func TestAnimatedMaskLoops(t *testing.T) {
+	full := image.Image(solidFrame(4, 4, red))
+	mask := &maskAnimation{frames: []*image.Image{&full, &full, &full}, delays: []int{10, 10, 10}}
+
+	// A still component is shown for as long as its mask takes to play
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{{Mask: &entity.Mask{URL: "mask.gif"}}}}
+	output := buildTimeline(request, [][]int{{}}, [][]*image.Image{blankFrames(1)}, []*maskAnimation{mask})
+	assert.Equal(t, []int{0, 10, 20}, output.times)
+	assert.Equal(t, 2, output.maskAt(0, 2))
+}
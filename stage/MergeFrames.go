@@ -0,0 +1,68 @@
+package stage
+
+import (
+	"github.com/fogleman/gg"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"log"
+	"math"
+)
+
+// MergeFrames merges runs of consecutive frames that are the same into a single frame shown for all of their delays,
+// so they aren't quantised and encoded more than once. Frames are the same if no channel of any pixel differs by
+// more than the tolerance from the first frame of the run
+func MergeFrames(outputContexts []*gg.Context, outputDelay []int, tolerance int) ([]*gg.Context, []int) {
+	if len(outputContexts) < 2 || tolerance < 0 {
+		return outputContexts, outputDelay
+	}
+
+	mergedContexts := []*gg.Context{outputContexts[0]}
+	mergedDelay := []int{frameDelay(outputDelay, 0)}
+	for i := 1; i < len(outputContexts); i++ {
+		last := len(mergedContexts) - 1
+		// GIFs store delays as 16 bits, so a run that would be longer than that starts a new frame
+		fits := mergedDelay[last]+frameDelay(outputDelay, i) <= math.MaxUint16
+		if fits && framesMatch(mergedContexts[last].Image(), outputContexts[i].Image(), tolerance) {
+			mergedDelay[last] += frameDelay(outputDelay, i)
+			continue
+		}
+		mergedContexts = append(mergedContexts, outputContexts[i])
+		mergedDelay = append(mergedDelay, frameDelay(outputDelay, i))
+	}
+
+	if len(mergedContexts) != len(outputContexts) {
+		log.Printf("Merged %d frames into %d\n", len(outputContexts), len(mergedContexts))
+	}
+	return mergedContexts, mergedDelay
+}
+
+// CanMerge checks the request doesn't give a disposal for each frame, which would no longer line up once merged
+func CanMerge(request *entity.ImageRequest) bool {
+	_, perFrame := request.Disposal.([]interface{})
+	return !perFrame
+}
+
+// frameDelay gets the delay of a frame, or the default if it has none
+func frameDelay(delays []int, frameNum int) int {
+	if frameNum < len(delays) && delays[frameNum] > 0 {
+		return delays[frameNum]
+	}
+	return helper.DefaultFrameDelay
+}
+
+// framesMatch checks if every channel of every pixel is within tolerance of the other frame
+func framesMatch(image1 image.Image, image2 image.Image, tolerance int) bool {
+	rgba1, ok1 := image1.(*image.RGBA)
+	rgba2, ok2 := image2.(*image.RGBA)
+	if !ok1 || !ok2 || rgba1.Rect != rgba2.Rect {
+		return false
+	}
+	for i, value := range rgba1.Pix {
+		difference := int(value) - int(rgba2.Pix[i])
+		if difference > tolerance || difference < -tolerance {
+			return false
+		}
+	}
+	return true
+}
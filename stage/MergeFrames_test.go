@@ -0,0 +1,40 @@
+package stage
+
+import (
+	"github.com/fogleman/gg"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image/color"
+	"testing"
+)
+
+func TestMergeFrames(t *testing.T) {
+	nearlyRed := color.RGBA{R: 252, A: 255}
+	contexts := []*gg.Context{colourContext(red), colourContext(red), colourContext(blue), colourContext(nearlyRed), colourContext(red)}
+	delays := []int{5, 5, 10, 0, 20}
+
+	merged, mergedDelay := MergeFrames(contexts, delays, 0)
+	assert.Equal(t, []*gg.Context{contexts[0], contexts[2], contexts[3], contexts[4]}, merged)
+	assert.Equal(t, []int{10, 10, 10, 20}, mergedDelay)
+
+	merged, mergedDelay = MergeFrames(contexts, delays, 3)
+	assert.Equal(t, []*gg.Context{contexts[0], contexts[2], contexts[3]}, merged)
+	assert.Equal(t, []int{10, 10, 30}, mergedDelay)
+
+	merged, mergedDelay = MergeFrames(contexts, delays, -1)
+	assert.Len(t, merged, 5)
+	assert.Equal(t, delays, mergedDelay)
+}
+
+func TestMergeFramesDelayLimit(t *testing.T) {
+	contexts := []*gg.Context{colourContext(red), colourContext(red), colourContext(red)}
+	merged, mergedDelay := MergeFrames(contexts, []int{40000, 40000, 20000}, 0)
+	assert.Len(t, merged, 2)
+	assert.Equal(t, []int{40000, 60000}, mergedDelay)
+}
+
+func TestCanMerge(t *testing.T) {
+	assert.True(t, CanMerge(&entity.ImageRequest{}))
+	assert.True(t, CanMerge(&entity.ImageRequest{Disposal: "background"}))
+	assert.False(t, CanMerge(&entity.ImageRequest{Disposal: []interface{}{"none", "background"}}))
+}
@@ -0,0 +1,169 @@
+package stage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"log"
+)
+
+const exifOrientationTag = 0x0112
+
+// readOrientation finds the EXIF orientation of a JPEG, PNG or WebP, returning 1 (normal) if there isn't one
+func readOrientation(body []byte, format string) int {
+	var exif []byte
+	switch format {
+	case "jpeg":
+		exif = findJPEGExif(body)
+	case "png":
+		chunks, err := readPNGChunks(body)
+		if err != nil {
+			return 1
+		}
+		for _, chunk := range chunks {
+			if chunk.chunkType == "eXIf" {
+				exif = chunk.data
+			}
+		}
+	case "webp":
+		chunks, err := readWebPChunks(body)
+		if err != nil {
+			return 1
+		}
+		for _, chunk := range chunks {
+			if chunk.fourCC == "EXIF" {
+				exif = chunk.data
+			}
+		}
+	}
+
+	// Some encoders keep the JPEG style header when writing EXIF into other formats
+	exif = bytes.TrimPrefix(exif, []byte("Exif\x00\x00"))
+	return parseExifOrientation(exif)
+}
+
+// findJPEGExif walks the JPEG markers up until the image data looking for an APP1 Exif segment
+func findJPEGExif(body []byte) []byte {
+	if len(body) < 4 || body[0] != 0xFF || body[1] != 0xD8 {
+		return nil
+	}
+	offset := 2
+	for offset+4 <= len(body) {
+		if body[offset] != 0xFF {
+			return nil
+		}
+		marker := body[offset+1]
+		// Start of scan, the rest is image data
+		if marker == 0xDA {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(body[offset+2 : offset+4]))
+		if length < 2 || offset+2+length > len(body) {
+			return nil
+		}
+		segment := body[offset+4 : offset+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment
+		}
+		offset += 2 + length
+	}
+	return nil
+}
+
+// parseExifOrientation reads the orientation tag out of the first IFD of a TIFF structure
+func parseExifOrientation(exif []byte) int {
+	if len(exif) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(exif[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int(order.Uint32(exif[4:8]))
+	if ifdOffset < 8 || ifdOffset+2 > len(exif) {
+		return 1
+	}
+
+	entries := int(order.Uint16(exif[ifdOffset : ifdOffset+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(exif) {
+			return 1
+		}
+		if order.Uint16(exif[entry:entry+2]) == exifOrientationTag {
+			orientation := int(order.Uint16(exif[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientImage transforms a frame so that it's the right way up for the given EXIF orientation
+func orientImage(frame image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return frame
+	}
+
+	bounds := frame.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Rect, frame, bounds.Min, draw.Src)
+
+	// Orientations 5 to 8 are rotated by 90 degrees, so the output dimensions are swapped
+	outputWidth, outputHeight := width, height
+	if orientation >= 5 {
+		outputWidth, outputHeight = height, width
+	}
+	output := image.NewRGBA(image.Rect(0, 0, outputWidth, outputHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // Rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // Mirrored horizontally and rotated 270 clockwise
+				dx, dy = y, x
+			case 6: // Rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // Mirrored horizontally and rotated 90 clockwise
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated 270 clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(output.Pix[output.PixOffset(dx, dy):output.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+
+	return output
+}
+
+// orientFrames normalises every frame according to the EXIF orientation of the input
+func orientFrames(frames []*image.Image, orientation int) []*image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return frames
+	}
+	log.Println("Applying EXIF orientation", orientation)
+	output := make([]*image.Image, len(frames))
+	for i, frame := range frames {
+		oriented := orientImage(*frame, orientation)
+		output[i] = &oriented
+	}
+	return output
+}
@@ -0,0 +1,82 @@
+package stage
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// orientedJPEG encodes a 20x10 JPEG that is red on the left half and blue on the right,
+// with an APP1 segment holding the given orientation
+func orientedJPEG(t *testing.T, orientation byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			if x < 10 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00")
+	exif = append(exif, orientation, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte{0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+
+	body := buf.Bytes()
+	return append(append(append([]byte{}, body[:2]...), segment...), body[2:]...)
+}
+
+func isMostlyRed(colour color.Color) bool {
+	r, _, b, _ := colour.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	body := orientedJPEG(t, 6)
+	assert.Equal(t, 6, readOrientation(body, "jpeg"))
+
+	frames, _, err := getImage(bytes.NewReader(body), &entity.ImageComponent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotated 90 degrees clockwise, so the red half ends up on top
+	assert.Equal(t, image.Rect(0, 0, 10, 20), (*frames[0]).Bounds())
+	assert.True(t, isMostlyRed((*frames[0]).At(5, 2)))
+	assert.False(t, isMostlyRed((*frames[0]).At(5, 17)))
+}
+
+func TestJPEGOrientationIgnored(t *testing.T) {
+	frames, _, err := getImage(bytes.NewReader(orientedJPEG(t, 6)), &entity.ImageComponent{IgnoreOrientation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 20, 10), (*frames[0]).Bounds())
+}
+
+func TestOrientImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, red)
+	expected := map[int]image.Point{
+		2: {X: 2, Y: 0},
+		3: {X: 2, Y: 1},
+		4: {X: 0, Y: 1},
+		5: {X: 0, Y: 0},
+		6: {X: 1, Y: 0},
+		7: {X: 1, Y: 2},
+		8: {X: 0, Y: 2},
+	}
+	for orientation, point := range expected {
+		oriented := orientImage(img, orientation)
+		assert.Equal(t, red, oriented.At(point.X, point.Y), "orientation %d", orientation)
+	}
+}
@@ -0,0 +1,117 @@
+package stage
+
+import (
+	"errors"
+	"fmt"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image/gif"
+	"math"
+)
+
+// ErrInvalidOutput is returned when the request asks for output that a GIF can't do
+var ErrInvalidOutput = errors.New("invalid output settings")
+
+// GIFs can't show a frame for less than 2 centiseconds, most viewers slow them down to 10 instead
+const _minimumDelay = 2
+
+var disposals = map[string]byte{
+	"none":       gif.DisposalNone,
+	"background": gif.DisposalBackground,
+	"previous":   gif.DisposalPrevious,
+}
+
+// ValidateOutput checks the loop count, delays and disposal in the request can be encoded
+func ValidateOutput(request *entity.ImageRequest) error {
+	// The loop extension stores how many times to repeat after the first play as 16 bits
+	if request.Loops < 0 || request.Loops > math.MaxUint16+1 {
+		return fmt.Errorf("%w: loops must be between 0 and %d", ErrInvalidOutput, math.MaxUint16+1)
+	}
+	if request.Delay < 0 || (request.Delay > 0 && request.Delay < _minimumDelay) || request.Delay > math.MaxUint16 {
+		return fmt.Errorf("%w: delay must be between %d and %d", ErrInvalidOutput, _minimumDelay, math.MaxUint16)
+	}
+	if request.FPS < 0 || request.FPS > 100/_minimumDelay {
+		return fmt.Errorf("%w: fps must be at most %d", ErrInvalidOutput, 100/_minimumDelay)
+	}
+	for _, delay := range request.Delays {
+		if delay < 0 || (delay > 0 && delay < _minimumDelay) || delay > math.MaxUint16 {
+			return fmt.Errorf("%w: frame delays must be between %d and %d", ErrInvalidOutput, _minimumDelay, math.MaxUint16)
+		}
+	}
+
+	switch disposal := request.Disposal.(type) {
+	case nil:
+	case string:
+		if _, ok := disposals[disposal]; !ok && disposal != "auto" {
+			return fmt.Errorf("%w: unknown disposal %s", ErrInvalidOutput, disposal)
+		}
+	case []interface{}:
+		for _, frameDisposal := range disposal {
+			name, _ := frameDisposal.(string)
+			if _, ok := disposals[name]; !ok {
+				return fmt.Errorf("%w: unknown disposal %v", ErrInvalidOutput, frameDisposal)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: disposal must be a string or a list", ErrInvalidOutput)
+	}
+	return nil
+}
+
+// overrideDelays replaces the delay of each frame that has one set in the request
+func overrideDelays(delays []int, overrides []int) []int {
+	for i, delay := range overrides {
+		if i < len(delays) && delay > 0 {
+			delays[i] = delay
+		}
+	}
+	return delays
+}
+
+// GIFLoopCount turns the number of times the animation plays into a GIF loop count, which is the number of repeats
+// with 0 meaning forever and -1 meaning none
+func GIFLoopCount(request *entity.ImageRequest) int {
+	if request.Loops == 0 {
+		return 0
+	}
+	if request.Loops == 1 {
+		return -1
+	}
+	return request.Loops - 1
+}
+
+// GIFDisposal gets the disposal for a frame, using the request's if it has one. Otherwise frames are left in place if
+// they've been diffed, or cleared to the background so transparent components don't smear
+func GIFDisposal(request *entity.ImageRequest, frameNum int, diffed bool) byte {
+	switch disposal := request.Disposal.(type) {
+	case string:
+		if value, ok := disposals[disposal]; ok {
+			return value
+		}
+	case []interface{}:
+		if frameNum < len(disposal) {
+			name, _ := disposal[frameNum].(string)
+			if value, ok := disposals[name]; ok {
+				return value
+			}
+		}
+	}
+	if diffed {
+		return gif.DisposalNone
+	}
+	return gif.DisposalBackground
+}
+
+// CanDiff checks the request's disposal keeps every frame in place, which diffing relies on
+func CanDiff(request *entity.ImageRequest) bool {
+	switch disposal := request.Disposal.(type) {
+	case string:
+		return disposal == "none" || disposal == "auto"
+	case []interface{}:
+		for _, frameDisposal := range disposal {
+			if frameDisposal != "none" {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package stage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image/gif"
+	"testing"
+)
+
+func TestValidateOutput(t *testing.T) {
+	assert.NoError(t, ValidateOutput(&entity.ImageRequest{}))
+	assert.NoError(t, ValidateOutput(&entity.ImageRequest{Loops: 3, FPS: 25, Delays: []int{0, 5}, Disposal: "previous"}))
+	assert.NoError(t, ValidateOutput(&entity.ImageRequest{Disposal: []interface{}{"none", "background"}}))
+
+	for _, request := range []*entity.ImageRequest{
+		{Loops: -1},
+		{Delay: 1},
+		{FPS: 60},
+		{Delays: []int{10, 1}},
+		{Disposal: "sideways"},
+		{Disposal: []interface{}{"none", float64(2)}},
+		{Disposal: float64(1)},
+	} {
+		assert.True(t, errors.Is(ValidateOutput(request), ErrInvalidOutput), "%+v", request)
+	}
+}
+
+func TestGIFLoopCount(t *testing.T) {
+	assert.Equal(t, 0, GIFLoopCount(&entity.ImageRequest{}))
+	assert.Equal(t, -1, GIFLoopCount(&entity.ImageRequest{Loops: 1}))
+	assert.Equal(t, 2, GIFLoopCount(&entity.ImageRequest{Loops: 3}))
+}
+
+func TestGIFDisposal(t *testing.T) {
+	assert.Equal(t, byte(gif.DisposalNone), GIFDisposal(&entity.ImageRequest{}, 0, true))
+	assert.Equal(t, byte(gif.DisposalBackground), GIFDisposal(&entity.ImageRequest{Disposal: "auto"}, 0, false))
+	assert.Equal(t, byte(gif.DisposalPrevious), GIFDisposal(&entity.ImageRequest{Disposal: "previous"}, 0, true))
+
+	perFrame := &entity.ImageRequest{Disposal: []interface{}{"none", "background"}}
+	assert.Equal(t, byte(gif.DisposalNone), GIFDisposal(perFrame, 0, false))
+	assert.Equal(t, byte(gif.DisposalBackground), GIFDisposal(perFrame, 1, true))
+	// Frames past the end of the list fall back to the default
+	assert.Equal(t, byte(gif.DisposalNone), GIFDisposal(perFrame, 2, true))
+	assert.False(t, CanDiff(perFrame))
+	assert.True(t, CanDiff(&entity.ImageRequest{Disposal: []interface{}{"none"}}))
+}
+
+func TestRequestDelays(t *testing.T) {
+	component := square(0, 0, 10, "#ff0000")
+	component.Filters = []*entity.Filter{{Name: "spin", Arguments: map[string]interface{}{"frames": float64(4)}}}
+	_, outputDelay, _, err := Render(&entity.ImageRequest{ImageComponents: []*entity.ImageComponent{component}, Delays: []int{20, 0, 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{20, 5, 30, 5}, outputDelay)
+
+	// Static components which appear later are timed in frames of the request's delay
+	late := square(0, 0, 10, "#0000ff")
+	late.Start = float64(2)
+	_, outputDelay, _, err = Render(&entity.ImageRequest{ImageComponents: []*entity.ImageComponent{square(0, 0, 10, "#ff0000"), late}, FPS: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{8, 4}, outputDelay)
+}
@@ -2,6 +2,8 @@ package stage
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/getsentry/sentry-go"
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,13 +12,13 @@ import (
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/filter"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
 	"image"
-	"image/gif"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"path"
+	"strings"
 	"time"
 )
 
@@ -41,24 +43,60 @@ var (
 	})
 )
 
+// Lets filters load other images the same way as components
+func init() {
+	filter.LoadImage = getComponentImage
+}
+
 // Does the BeforeStacking filters
 func ProcessBeforeStackingFilters(request *entity.ImageRequest) {
 	for _, component := range request.ImageComponents {
-		for _, filterData := range component.Filters {
-			var filterObj interface{}
-			var ok bool
-			if filterObj, ok = filter.Filters[filterData.Name]; !ok {
-				log.Println("Unknown filter type", filterData)
-				sentry.CaptureMessage(fmt.Sprintf("Unknown filter type '%s'", filterData))
-				continue
-			}
-			if processFilter, ok := filterObj.(filter.BeforeStacking); ok {
-				beforeStackingStart := time.Now()
-				processFilter.BeforeStacking(request, component, filterData)
-				beforeStackingFilterDuration.Observe(float64(time.Since(beforeStackingStart).Milliseconds()))
+		processComponentBeforeStackingFilters(request, component)
+	}
+}
+
+// Does the BeforeStacking filters of a single component
+func processComponentBeforeStackingFilters(request *entity.ImageRequest, component *entity.ImageComponent) {
+	for _, filterData := range component.Filters {
+		var filterObj interface{}
+		var ok bool
+		if filterObj, ok = filter.Filters[filterData.Name]; !ok {
+			log.Println("Unknown filter type", filterData)
+			sentry.CaptureMessage(fmt.Sprintf("Unknown filter type '%s'", filterData))
+			continue
+		}
+		if processFilter, ok := filterObj.(filter.BeforeStacking); ok {
+			beforeStackingStart := time.Now()
+			processFilter.BeforeStacking(request, component, filterData)
+			beforeStackingFilterDuration.Observe(float64(time.Since(beforeStackingStart).Milliseconds()))
+		}
+	}
+}
+
+// validateFilters checks the arguments of any filters that can be checked before rendering
+func validateFilters(filters []*entity.Filter) error {
+	for _, filterData := range filters {
+		if validator, ok := filter.Filters[filterData.Name].(filter.Validator); ok {
+			if exception := validator.Validate(filterData.Arguments); exception != nil {
+				return exception
 			}
 		}
+	}
+	return nil
+}
 
+// Does the AfterStacking filters, which can change the frames and delays of a component
+func processAfterStackingFilters(request *entity.ImageRequest, component *entity.ImageComponent, frameImages *[]*image.Image, frameDelay *[]int) {
+	for _, filterData := range component.Filters {
+		var filterObj interface{}
+		var ok bool
+		if filterObj, ok = filter.Filters[filterData.Name]; !ok {
+			log.Println("Unknown filter type", filterData)
+			continue
+		}
+		if processFilter, ok := filterObj.(filter.AfterStacking); ok {
+			processFilter.AfterStacking(filterData, request, component, frameImages, frameDelay)
+		}
 	}
 }
 
@@ -91,18 +129,30 @@ func MapComponentFrames(request *entity.ImageRequest) ([][]int, [][]*image.Image
 			component.Position.Height = float64(0)
 		}
 
-		if component.URL == "" {
-			continue
+		// Relative sizes are needed before loading so SVGs can be rasterised at the size they're drawn
+		if relative, ok := component.Position.Width.(string); ok && request.Width != 0 {
+			component.Position.Width = helper.GetRelativeDimension(request.Width, relative)
+		}
+
+		if relative, ok := component.Position.Height.(string); ok && request.Height != 0 {
+			component.Position.Height = helper.GetRelativeDimension(request.Height, relative)
 		}
 
-		// decide which function to get the image with (explicitly typed)
-		var getImageFunc = getImageURL
-		if component.Local {
-			getImageFunc = getLocalImage
+		if component.Resample == "" {
+			component.Resample = request.Resample
 		}
 
-		// get the image, returns all the frames if the image is a gif
-		frameImages, frameDelay, exception := getImageFunc(component.URL)
+		var frameImages []*image.Image
+		var frameDelay []int
+		var exception error
+		if component.Type == "group" {
+			frameImages, frameDelay, exception = renderGroup(request, component)
+		} else if component.Type == "slideshow" {
+			frameImages, frameDelay, exception = renderSlideshow(request, component)
+		} else if component.URL != "" {
+			// get the image, returns all the frames if the image is a gif
+			frameImages, frameDelay, exception = getComponentImage(component)
+		}
 		if exception != nil {
 			log.Println("Unable to get image:", exception)
 			sentry.CaptureException(exception)
@@ -110,18 +160,20 @@ func MapComponentFrames(request *entity.ImageRequest) ([][]int, [][]*image.Image
 			//return &entity.ImageResult{Error: "get_image"}
 		}
 
-		for _, filterData := range component.Filters {
-			var filterObj interface{}
-			var ok bool
-			if filterObj, ok = filter.Filters[filterData.Name]; !ok {
-				log.Println("Unknown filter type", filterData)
-				continue
-			}
-			if processFilter, ok := filterObj.(filter.AfterStacking); ok {
-				processFilter.AfterStacking(filterData, request, component, &frameImages, &frameDelay)
-			}
+		if len(frameImages) == 0 {
+			continue
+		}
+
+		if component.Crop != nil {
+			frameImages = cropFrames(frameImages, component.Crop)
+		}
+
+		if component.Sprite != nil {
+			frameImages, frameDelay = sliceSprite(frameImages, frameDelay, component.Sprite, helper.GetRequestDelay(request))
 		}
 
+		processAfterStackingFilters(request, component, &frameImages, &frameDelay)
+
 		go helper.WriteDebugPNG(*frameImages[0], fmt.Sprintf("comp-%d.frame-0.AfterStacking", comp))
 
 		// Set the component width/height to the width/height of the first frame if it's not currently set
@@ -141,31 +193,88 @@ func MapComponentFrames(request *entity.ImageRequest) ([][]int, [][]*image.Image
 	return componentFrameDelays, componentFrameImages, nil
 }
 
-func getImageURL(url string) ([]*image.Image, []int, error) {
-	response, err := http.Get(url)
+// getComponentImage decides which function to get the image with and returns every frame
+func getComponentImage(component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	// (explicitly typed)
+	var getImageFunc = getImageURL
+	if component.Local {
+		getImageFunc = getLocalImage
+	} else if isInlineImage(component.URL) {
+		getImageFunc = getInlineImage
+	}
+	return getImageFunc(component)
+}
+
+func getImageURL(component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	response, err := http.Get(component.URL)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer response.Body.Close()
-	return getImage(response.Body)
+	return getImage(response.Body, component)
 }
 
-func getLocalImage(url string) ([]*image.Image, []int, error) {
-	file, err := os.Open(path.Join("res", url))
+func getLocalImage(component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	resourcePath, err := helper.ResolveResource(component.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(resourcePath)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer file.Close()
-	return getImage(file)
+	return getImage(file, component)
+}
+
+// isInlineImage checks if the URL is a data URL or a raw SVG document rather than somewhere to get the image from
+func isInlineImage(url string) bool {
+	return strings.HasPrefix(url, "data:") || isSVG([]byte(url))
+}
+
+// getInlineImage decodes an image that was sent inside the request itself
+func getInlineImage(component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	if !strings.HasPrefix(component.URL, "data:") {
+		return getImage(strings.NewReader(component.URL), component)
+	}
+
+	// data:[<mediatype>][;base64],<data>
+	separator := strings.Index(component.URL, ",")
+	if separator == -1 {
+		return nil, nil, errors.New("malformed data URL")
+	}
+	header := component.URL[len("data:"):separator]
+	data := component.URL[separator+1:]
+
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return getImage(bytes.NewReader(decoded), component)
+	}
+
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getImage(strings.NewReader(decoded), component)
 }
 
-// Takes an input image in a supported format and redraws it as an array of NRGBA frames
-func getImage(input io.Reader) ([]*image.Image, []int, error) {
+// Takes an input image in a supported format and redraws it as an array of RGBA frames
+func getImage(input io.Reader, component *entity.ImageComponent) ([]*image.Image, []int, error) {
 	body, err := ioutil.ReadAll(input)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// SVGs aren't registered with image.Decode as they need to know the size to rasterise at
+	if isSVG(body) {
+		width, _ := component.Position.Width.(float64)
+		height, _ := component.Position.Height.(float64)
+		return decodeSVG(body, width, height)
+	}
+
 	reader := bytes.NewReader(body)
 	_, format, err := image.DecodeConfig(reader)
 	if err != nil {
@@ -177,52 +286,27 @@ func getImage(input io.Reader) ([]*image.Image, []int, error) {
 		return nil, nil, err
 	}
 
-	if format == "gif" {
-		log.Println("Decoding the gif...")
-		gifFile, err := gif.DecodeAll(reader)
-		if err != nil {
+	frames, delays, err := decodeFrames(reader, body, format)
+	if err != nil {
+		return nil, nil, err
+	}
 
-			log.Printf("Error decoding gif: %s\n", err)
-			return nil, nil, err
-		}
-		output := make([]*image.Image, len(gifFile.Image))
-
-		log.Println("Stacking frames...")
-		// use tmp to hold a stacked version of the frame
-		firstFrame := gifFile.Image[0]
-		frameBg := image.NewNRGBA(firstFrame.Bounds())
-
-		// Convert the gif into a series of NRGBA frames
-		for i, img := range gifFile.Image {
-			disposalMethod := gifFile.Disposal[i]
-			// Depending on the disposal method, reset frameBg to a blank slate
-			//  - DisposalNone: sum of previous frames
-			//  - DisposalBackground or DisposalPrevious: blank
-			if disposalMethod != 0 && disposalMethod != gif.DisposalNone {
-				frameBg = image.NewNRGBA(img.Bounds())
-			}
-			// Iterate over the palette image, converting it into sets of RGBA bytes
-			for i, colour := range img.Pix {
-				r, g, b, a := img.Palette[colour].RGBA()
-				// Skip drawing transparent pixels to avoid overwriting the previous frame in those Disposal modes
-				if a == 0 {
-					continue
-				}
-				// Each pixel contains 4 bytes
-				p := i * 4
-				frameBg.Pix[p] = uint8(r)
-				frameBg.Pix[p+1] = uint8(g)
-				frameBg.Pix[p+2] = uint8(b)
-				frameBg.Pix[p+3] = uint8(a)
-			}
-			// Set the output to a clone of the current state of frameBg
-			// Copy the pix array for speed
-			clone := image.NewNRGBA(frameBg.Bounds())
-			copy(clone.Pix, frameBg.Pix)
-			genericImage := image.Image(clone)
-			output[i] = &genericImage
-		}
-		return output, gifFile.Delay, nil
+	if !component.IgnoreOrientation {
+		frames = orientFrames(frames, readOrientation(body, format))
+	}
+
+	return frames, delays, nil
+}
+
+// decodeFrames decodes each frame of a raster image, picking the decoder for animated formats
+func decodeFrames(reader io.Reader, body []byte, format string) ([]*image.Image, []int, error) {
+	switch {
+	case format == "gif":
+		return decodeGIF(reader)
+	case format == "png" && isAnimatedPNG(body):
+		return decodeAPNG(body)
+	case format == "webp" && isAnimatedWebP(body):
+		return decodeAnimatedWebP(body)
 	}
 
 	img, _, err := image.Decode(reader)
@@ -0,0 +1,233 @@
+package stage
+
+import (
+	"fmt"
+	"github.com/fogleman/gg"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/filter"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"log"
+	"time"
+)
+
+var (
+	componentDrawDuration = promauto.NewSummary(prometheus.SummaryOpts{
+		Namespace: "image_renderer",
+		Name:      "component_draw_duration",
+		Help:      "Duration taken to stack component images",
+	})
+
+	beforeRenderFilterDuration = promauto.NewSummary(prometheus.SummaryOpts{
+		Namespace: "image_renderer",
+		Name:      "filter_before_render_duration",
+		Help:      "Duration taken to process BeforeRender filters",
+	})
+)
+
+// Render loads every component in the request and stacks them onto the canvas, returning the context and delay
+// for each frame of the output, and whether the frames should be diffed
+func Render(request *entity.ImageRequest) ([]*gg.Context, []int, bool, error) {
+	if exception := validateCanvasFilters(request); exception != nil {
+		return nil, nil, false, exception
+	}
+	for _, component := range request.ImageComponents {
+		if exception := validateFilters(component.Filters); exception != nil {
+			return nil, nil, false, exception
+		}
+		if exception := validateBlend(component.Blend); exception != nil {
+			return nil, nil, false, exception
+		}
+	}
+
+	if exception := ResolveLayouts(request); exception != nil {
+		return nil, nil, false, exception
+	}
+	sortComponents(request.ImageComponents)
+
+	ProcessBeforeStackingFilters(request)
+
+	componentFrameDelays, componentFrameImages, exception := MapComponentFrames(request)
+
+	if exception != nil {
+		return nil, nil, false, exception
+	}
+
+	masks, exception := loadMasks(request)
+	if exception != nil {
+		return nil, nil, false, exception
+	}
+
+	ExpandCanvas(request)
+	AutoSizeCanvas(request)
+
+	// Every component's frames are placed on a common clock to decide the output frames
+	outputTimeline := buildTimeline(request, componentFrameDelays, componentFrameImages, masks)
+
+	// holds all the contexts for each frame of the final output image
+	outputContexts := make([]*gg.Context, len(outputTimeline.times))
+
+	// Used to determine if the diff should be calculated
+	shouldDiff := false
+
+	// Without components there is only the canvas, which can't be sized from anything else
+	if len(request.ImageComponents) == 0 {
+		if request.Width == 0 || request.Height == 0 {
+			log.Println("Nothing to render")
+			return []*gg.Context{}, []int{}, false, nil
+		}
+		createOutputContexts(request, nil, outputContexts)
+	}
+
+	for comp, component := range request.ImageComponents {
+		componentDrawStart := time.Now()
+		// Only components with a background should be diffed
+		if component.URL != "" && component.Background != "" {
+			shouldDiff = true
+		}
+
+		frameImages := componentFrameImages[comp]
+
+		// Check for relative width/height and set to the correct value
+		if ppw, ok := component.Position.Width.(string); ok {
+			component.Position.Width = helper.GetRelativeDimension(request.Width, ppw)
+		}
+
+		if pph, ok := component.Position.Height.(string); ok {
+			component.Position.Height = helper.GetRelativeDimension(request.Height, pph)
+		}
+
+		// The first component decides the size of the canvas if there isn't one
+		if comp == 0 {
+			createOutputContexts(request, component, outputContexts)
+		}
+
+		var inputFrameCtx *gg.Context
+		lastFrame := -1
+		for outputFrame, outputCtx := range outputContexts {
+			frameNum := outputTimeline.frameAt(comp, outputFrame)
+			if frameNum < 0 {
+				continue
+			}
+
+			// The same input frame is often shown on several output frames, so only draw it once
+			if frameNum != lastFrame {
+				inputFrameCtx = newInputFrameContext(request, component, comp, frameImages, frameNum)
+				lastFrame = frameNum
+			}
+
+			// Animated masks play on the timeline alongside the component rather than frame by frame
+			var maskFrame *image.Image
+			if masks[comp] != nil {
+				maskFrame = masks[comp].frames[outputTimeline.maskAt(comp, outputFrame)]
+			}
+
+			RotateAndResize(inputFrameCtx, outputCtx, component, maskFrame)
+		}
+		componentDrawDuration.Observe(float64(time.Since(componentDrawStart).Milliseconds()))
+	}
+
+	outputDelay := overrideDelays(outputTimeline.delays, request.Delays)
+	if len(request.Filters) > 0 {
+		outputContexts, outputDelay = processCanvasFilters(request, outputContexts, outputDelay)
+	}
+
+	return outputContexts, outputDelay, shouldDiff, nil
+}
+
+// createOutputContexts sizes the canvas from the first component if it has no size, scaling the component down
+// if it is wider than the maximum width, then creates the context for each output frame.
+// The component is nil when the request has none, leaving the canvas at the request's size
+func createOutputContexts(request *entity.ImageRequest, component *entity.ImageComponent, outputContexts []*gg.Context) {
+	if component != nil {
+		sizeCanvasFromComponent(request, component)
+	}
+
+	for i := range outputContexts {
+		outputContexts[i] = gg.NewContext(request.Width, request.Height)
+		drawCanvasBackground(outputContexts[i], request)
+	}
+}
+
+// sizeCanvasFromComponent sets any missing canvas dimension to the component's, after scaling it down to the maximum width
+func sizeCanvasFromComponent(request *entity.ImageRequest, component *entity.ImageComponent) {
+	// Check for a MaxWidth param, or default to 1920 and resize the image accordingly
+	if request.MaxWidth > -1 && component.Position.Width != nil && component.Position.Height != nil {
+		if request.MaxWidth == 0 {
+			request.MaxWidth = 1920
+		}
+		componentWidth := int(component.Position.Width.(float64))
+		componentHeight := int(component.Position.Height.(float64))
+		if componentWidth > request.MaxWidth {
+			component.Position.Height = float64(request.MaxWidth * componentHeight / componentWidth)
+			component.Position.Width = float64(request.MaxWidth)
+		}
+	}
+
+	if request.Width == 0 && component.Position.Width != nil {
+		request.Width = int(component.Position.Width.(float64))
+	}
+	if request.Height == 0 && component.Position.Height != nil {
+		request.Height = int(component.Position.Height.(float64))
+	}
+}
+
+// newInputFrameContext draws a frame of a component onto its own context and applies its BeforeRender filters.
+// Components without an image get a blank context the size of the component
+func newInputFrameContext(request *entity.ImageRequest, component *entity.ImageComponent, comp int, frameImages []*image.Image, frameNum int) *gg.Context {
+	var ctx *gg.Context
+	if len(frameImages) == 0 {
+		ctx = gg.NewContext(int(component.Position.Width.(float64)), int(component.Position.Height.(float64)))
+		if comp == 0 {
+			if component.Background != "" {
+				ctx.SetHexColor(component.Background)
+				ctx.DrawRectangle(0, 0, float64(request.Width), float64(request.Height))
+				ctx.Fill()
+			}
+		}
+	} else {
+		img := frameImages[frameNum]
+		dx := (*img).Bounds().Dx()
+		dy := (*img).Bounds().Dy()
+		ctx = gg.NewContext(dx, dy)
+		// this is a replacement for me figuring out the actual problems
+		if component.Background != "" {
+			ctx.SetHexColor(component.Background)
+			ctx.DrawRectangle(0, 0, float64(dx), float64(dy))
+			ctx.Fill()
+		}
+		ctx.DrawImage(*img, 0, 0)
+	}
+
+	processBeforeRenderFilters(ctx, component, frameNum)
+
+	if request.Debug {
+		ctx.SetLineWidth(10)
+		ctx.SetHexColor("#ff0000")
+		ctx.DrawRectangle(0, 0, float64(ctx.Width()), float64(ctx.Height()))
+		ctx.Stroke()
+		ctx.DrawStringWrapped(fmt.Sprintf("%dx%d", ctx.Width(), ctx.Height()), float64(ctx.Width()), float64(ctx.Height()), 1, 1, float64(ctx.Width()), 1, gg.AlignLeft)
+	}
+	return ctx
+}
+
+// processBeforeRenderFilters applies any BeforeRender filters set for the component to the context
+func processBeforeRenderFilters(ctx *gg.Context, component *entity.ImageComponent, frameNum int) {
+	for _, filterObject := range component.Filters {
+		// check the filter exists and apply it
+		var filterObj interface{}
+		var ok bool
+		if filterObj, ok = filter.Filters[filterObject.Name]; !ok {
+			log.Println("Unknown filter type", filterObject)
+			continue
+		}
+		if processFilter, ok := filterObj.(filter.BeforeRender); ok {
+			log.Println("Applying filter", filterObject.Name, filterObject.Arguments)
+			beforeRenderFilterStart := time.Now()
+			processFilter.BeforeRender(ctx, filterObject.Arguments, frameNum, component)
+			beforeRenderFilterDuration.Observe(float64(time.Since(beforeRenderFilterStart).Milliseconds()))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package stage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/filter"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"testing"
+)
+
+func TestCanvasFilters(t *testing.T) {
+	component := square(0, 0, 20, "#ff0000")
+	component.Filters = []*entity.Filter{{Name: "slide", Arguments: map[string]interface{}{"frames": float64(4), "direction": "right"}}}
+	outputContexts, outputDelay, _, err := Render(&entity.ImageRequest{
+		Width:           40,
+		Height:          20,
+		ImageComponents: []*entity.ImageComponent{component},
+		Filters: []*entity.Filter{
+			{Name: "boomerang"},
+			{Name: "rectangle", Arguments: map[string]interface{}{"x": float64(30), "w": float64(10), "colour": "#0000ff"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, outputContexts, 6)
+	assert.Len(t, outputDelay, 6)
+	for frameNum, ctx := range outputContexts {
+		frame := ctx.Image()
+		// Drawn over the whole canvas rather than the component
+		assertPixel(t, &frame, 35, 10, blue)
+		// The slide finishes in the middle then plays back
+		if frameNum == 3 {
+			assertPixel(t, &frame, 10, 10, red)
+		} else if frameNum == 0 {
+			assertPixel(t, &frame, 10, 10, transparent)
+		}
+	}
+}
+
+// stackingRecorder is a BeforeStacking filter that records the components it ran on
+type stackingRecorder struct {
+	components *[]*entity.ImageComponent
+}
+
+func (s stackingRecorder) BeforeStacking(request *entity.ImageRequest, component *entity.ImageComponent, filter *entity.Filter) {
+	*s.components = append(*s.components, component)
+}
+
+func TestCanvasBeforeStackingFilters(t *testing.T) {
+	components := make([]*entity.ImageComponent, 0)
+	filter.Filters["record"] = stackingRecorder{components: &components}
+	defer delete(filter.Filters, "record")
+
+	_, _, _, err := Render(&entity.ImageRequest{
+		Width:           30,
+		Height:          20,
+		ImageComponents: []*entity.ImageComponent{square(0, 0, 20, "#ff0000")},
+		Filters:         []*entity.Filter{{Name: "record"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The canvas is passed as a component covering the whole image
+	assert.Len(t, components, 1)
+	assert.Equal(t, float64(30), components[0].Position.Width)
+}
+
+func TestUnknownCanvasFilter(t *testing.T) {
+	_, _, _, err := Render(&entity.ImageRequest{
+		ImageComponents: []*entity.ImageComponent{square(0, 0, 20, "#ff0000")},
+		Filters:         []*entity.Filter{{Name: "boomerang"}, {Name: "sparkle"}},
+	})
+	assert.True(t, errors.Is(err, ErrUnknownFilter))
+}
+
+func TestUnknownFont(t *testing.T) {
+	component := square(0, 0, 20, "#ff0000")
+	component.Filters = []*entity.Filter{{Name: "text", Arguments: map[string]interface{}{"content": "hi", "font": "../../main.go"}}}
+	_, _, _, err := Render(&entity.ImageRequest{ImageComponents: []*entity.ImageComponent{component}})
+	assert.True(t, errors.Is(err, helper.ErrUnknownResource))
+}
+
+func TestRenderWithoutComponents(t *testing.T) {
+	outputContexts, outputDelay, _, err := Render(&entity.ImageRequest{
+		Width:      10,
+		Height:     10,
+		Background: "#00ff00",
+		Filters:    []*entity.Filter{{Name: "rectangle", Arguments: map[string]interface{}{"w": float64(5), "colour": "#0000ff"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, outputContexts, 1)
+	assert.Len(t, outputDelay, 1)
+	frame := outputContexts[0].Image()
+	assertPixel(t, &frame, 2, 5, blue)
+	assertPixel(t, &frame, 8, 5, green)
+
+	// Without a size there's nothing to draw on
+	outputContexts, outputDelay, _, err = Render(&entity.ImageRequest{Background: "#00ff00"})
+	assert.NoError(t, err)
+	assert.Empty(t, outputContexts)
+	assert.Empty(t, outputDelay)
+}
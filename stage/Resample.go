@@ -0,0 +1,58 @@
+package stage
+
+import (
+	"golang.org/x/image/draw"
+	"image"
+	"log"
+	"math"
+)
+
+// lanczos is a 3-lobed Lanczos kernel, sharper than Catmull-Rom for large downscales but slower
+var lanczos = &draw.Kernel{
+	Support: 3,
+	At: func(t float64) float64 {
+		if t == 0 {
+			return 1
+		}
+		if t >= 3 {
+			return 0
+		}
+		t *= math.Pi
+		return 3 * math.Sin(t) * math.Sin(t/3) / (t * t)
+	},
+}
+
+var resamplers = map[string]draw.Interpolator{
+	"nearest":         draw.NearestNeighbor,
+	"approx-bilinear": draw.ApproxBiLinear,
+	"bilinear":        draw.BiLinear,
+	"catmull-rom":     draw.CatmullRom,
+	"lanczos":         lanczos,
+}
+
+// getResampler picks the interpolator for a resample mode, defaulting to bilinear. With auto or pixel an upscale by
+// a whole number uses nearest neighbour so pixel art and emojis stay crisp
+func getResampler(mode string, source image.Rectangle, target image.Rectangle) draw.Interpolator {
+	if resampler, ok := resamplers[mode]; ok {
+		return resampler
+	}
+	if mode == "auto" || mode == "pixel" {
+		if isIntegerUpscale(source, target) {
+			return draw.NearestNeighbor
+		}
+	} else if mode != "" {
+		log.Println("Unknown resample mode", mode)
+	}
+	return draw.BiLinear
+}
+
+// isIntegerUpscale checks if the target is the source scaled up by the same whole number in each direction
+func isIntegerUpscale(source image.Rectangle, target image.Rectangle) bool {
+	if source.Dx() == 0 || source.Dy() == 0 || target.Dx() <= source.Dx() {
+		return false
+	}
+	if target.Dx()%source.Dx() != 0 || target.Dy()%source.Dy() != 0 {
+		return false
+	}
+	return target.Dx()/source.Dx() == target.Dy()/source.Dy()
+}
@@ -0,0 +1,28 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/draw"
+	"image"
+	"testing"
+)
+
+func TestIntegerUpscaleIsCrisp(t *testing.T) {
+	assert.True(t, isIntegerUpscale(image.Rect(0, 0, 16, 8), image.Rect(0, 0, 64, 32)))
+	assert.False(t, isIntegerUpscale(image.Rect(0, 0, 16, 8), image.Rect(0, 0, 64, 16)))
+	assert.False(t, isIntegerUpscale(image.Rect(0, 0, 16, 8), image.Rect(0, 0, 8, 4)))
+	assert.Equal(t, draw.BiLinear, getResampler("auto", image.Rect(0, 0, 16, 8), image.Rect(0, 0, 20, 10)))
+	assert.Equal(t, draw.NearestNeighbor, getResampler("pixel", image.Rect(0, 0, 16, 8), image.Rect(0, 0, 64, 32)))
+	// Without a mode, existing templates keep being smoothed
+	assert.Equal(t, draw.BiLinear, getResampler("", image.Rect(0, 0, 128, 128), image.Rect(0, 0, 256, 256)))
+	assert.Equal(t, draw.CatmullRom, getResampler("catmull-rom", image.Rect(0, 0, 16, 8), image.Rect(0, 0, 64, 32)))
+
+	// A 2x2 checkerboard scaled up 4 times should have no blended pixels
+	checkerboard := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	checkerboard.SetRGBA(0, 0, red)
+	checkerboard.SetRGBA(1, 1, red)
+	scaled := fitFrame(checkerboard, 8, 8, "fill", "", "auto")
+	assert.Equal(t, red, scaled.RGBAAt(3, 3))
+	assert.Equal(t, transparent, scaled.RGBAAt(4, 3))
+	assert.Equal(t, red, scaled.RGBAAt(4, 4))
+}
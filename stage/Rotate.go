@@ -0,0 +1,117 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"log"
+	"math"
+)
+
+// rotationRadians gets the rotation of a component in radians, converting from degrees if needed
+func rotationRadians(component *entity.ImageComponent) float64 {
+	if component.RotationUnit == "deg" {
+		return component.Rotation * math.Pi / 180
+	}
+	return component.Rotation
+}
+
+// pivotPoint gets the point the component rotates about relative to its top left, which defaults to the centre
+func pivotPoint(component *entity.ImageComponent, width float64, height float64) (float64, float64) {
+	if component.Pivot == nil {
+		return width / 2, height / 2
+	}
+	return float64(relativeValue(component.Pivot.X, int(width), int(width/2))), float64(relativeValue(component.Pivot.Y, int(height), int(height/2)))
+}
+
+// componentBounds gets the bounding box of a component on the canvas once it has been rotated or transformed
+func componentBounds(component *entity.ImageComponent) (float64, float64, float64, float64) {
+	x, _ := component.Position.X.(float64)
+	y, _ := component.Position.Y.(float64)
+	width, _ := component.Position.Width.(float64)
+	height, _ := component.Position.Height.(float64)
+
+	if component.Transform != nil {
+		if minX, minY, maxX, maxY, ok := transformedBounds(component.Transform, width, height); ok {
+			return minX, minY, maxX, maxY
+		}
+	}
+
+	angle := rotationRadians(component)
+	if angle == 0 {
+		return x, y, x + width, y + height
+	}
+
+	pivotX, pivotY := pivotPoint(component, width, height)
+	sin, cos := math.Sincos(angle)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {width, 0}, {0, height}, {width, height}} {
+		dx := corner[0] - pivotX
+		dy := corner[1] - pivotY
+		cornerX := x + pivotX + dx*cos - dy*sin
+		cornerY := y + pivotY + dx*sin + dy*cos
+		minX = math.Min(minX, cornerX)
+		minY = math.Min(minY, cornerY)
+		maxX = math.Max(maxX, cornerX)
+		maxY = math.Max(maxY, cornerY)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// ExpandCanvas grows the canvas to fit every component once rotated, moving them all along if any of them
+// would be off the top or left of the canvas
+func ExpandCanvas(request *entity.ImageRequest) {
+	if !request.Expand || len(request.ImageComponents) == 0 {
+		return
+	}
+
+	// Without a size, the canvas is the size of the first component
+	minX, minY := 0.0, 0.0
+	maxX, maxY := float64(request.Width), float64(request.Height)
+	if request.Width == 0 {
+		maxX, _ = request.ImageComponents[0].Position.Width.(float64)
+	}
+	if request.Height == 0 {
+		maxY, _ = request.ImageComponents[0].Position.Height.(float64)
+	}
+
+	for _, component := range request.ImageComponents {
+		componentMinX, componentMinY, componentMaxX, componentMaxY := componentBounds(component)
+		minX = math.Min(minX, componentMinX)
+		minY = math.Min(minY, componentMinY)
+		maxX = math.Max(maxX, componentMaxX)
+		maxY = math.Max(maxY, componentMaxY)
+	}
+
+	offsetX := math.Ceil(-minX)
+	offsetY := math.Ceil(-minY)
+	moveComponents(request.ImageComponents, offsetX, offsetY)
+
+	request.Width = int(math.Ceil(maxX + offsetX))
+	request.Height = int(math.Ceil(maxY + offsetY))
+	log.Printf("Expanded canvas to %dx%d\n", request.Width, request.Height)
+}
+
+// moveComponents moves every component across the canvas, including where their transforms put them
+func moveComponents(components []*entity.ImageComponent, offsetX float64, offsetY float64) {
+	for _, component := range components {
+		if x, ok := component.Position.X.(float64); ok {
+			component.Position.X = x + offsetX
+		}
+		if y, ok := component.Position.Y.(float64); ok {
+			component.Position.Y = y + offsetY
+		}
+		if component.Transform == nil {
+			continue
+		}
+		if len(component.Transform.Matrix) == 6 {
+			component.Transform.Matrix[4] += offsetX
+			component.Transform.Matrix[5] += offsetY
+		}
+		for _, corner := range component.Transform.Corners {
+			if len(corner) >= 2 {
+				corner[0] += offsetX
+				corner[1] += offsetY
+			}
+		}
+	}
+}
@@ -3,42 +3,57 @@ package stage
 import (
 	"github.com/fogleman/gg"
 	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
-	"golang.org/x/image/draw"
 	"image"
+	"image/draw"
 	"log"
 )
 
-func RotateAndResize(inputFrameCtx *gg.Context, outputCtx *gg.Context, component *entity.ImageComponent) {
-	// move the specified component to its target position
-	outputCtx.RotateAbout(component.Rotation, component.Position.X.(float64), component.Position.Y.(float64))
-
-	// check if the frame needs to be resized
+func RotateAndResize(inputFrameCtx *gg.Context, outputCtx *gg.Context, component *entity.ImageComponent, maskFrame *image.Image) {
+	// resize the frame into the component's slot, which may letterbox or crop it depending on the fit mode
+	width := int(component.Position.Width.(float64))
+	height := int(component.Position.Height.(float64))
+	x := component.Position.X.(float64)
+	y := component.Position.Y.(float64)
 	var frameImage *image.RGBA
-	if component.Position.Width != inputFrameCtx.Width() || component.Position.Height != inputFrameCtx.Height() {
-		// make a rectangle with the target bounds
-		newSize := image.Rectangle{
-			Min: image.Point{
-				X: 0,
-				Y: 0,
-			},
-			Max: image.Point{
-				X: int(component.Position.Width.(float64)),
-				Y: int(component.Position.Height.(float64)),
-			},
-		}
+	// The input frame can be drawn to more than one output frame, so it's copied if the mask is going to change it
+	unmasked := component.Mask == nil && component.Opacity == nil
+	if rgbaImage, ok := inputFrameCtx.Image().(*image.RGBA); ok && unmasked && (component.Fit == "" || component.Fit == "fill") && rgbaImage.Rect.Dx() == width && rgbaImage.Rect.Dy() == height {
+		frameImage = rgbaImage
+	} else {
+		log.Println("New size:", width, height)
+		frameImage = fitFrame(inputFrameCtx.Image(), width, height, component.Fit, component.Anchor, component.Resample)
+	}
 
-		log.Println("New size:", newSize)
-		frameImage = image.NewRGBA(newSize)
+	applyMask(frameImage, component, maskFrame)
 
-		// scale the image
-		draw.BiLinear.Scale(frameImage, frameImage.Bounds(), inputFrameCtx.Image(), inputFrameCtx.Image().Bounds(), draw.Over, &draw.Options{})
-	} else {
-		frameImage = inputFrameCtx.Image().(*image.RGBA)
+	if component.Transform != nil {
+		log.Printf("Warping component %s\n", component.URL)
+		layer, bounds := warpFrame(frameImage, component.Transform, outputCtx.Width(), outputCtx.Height())
+		if isBlended(component.Blend) {
+			blendImage(outputCtx.Image().(*image.RGBA), layer, component.Blend)
+		} else {
+			draw.Draw(outputCtx.Image().(*image.RGBA), bounds, layer, bounds.Min, draw.Over)
+		}
+		return
 	}
 
 	log.Printf("Drawing component %s at %d %d\n", component.URL, component.Position.X, component.Position.Y)
-	outputCtx.DrawImage(frameImage, int(component.Position.X.(float64)), int(component.Position.Y.(float64)))
 
-	// Reset the rotation
-	outputCtx.RotateAbout(-component.Rotation, component.Position.X.(float64), component.Position.Y.(float64))
+	// rotate about the pivot, the resize has already happened so the rotation doesn't distort the image
+	angle := rotationRadians(component)
+	pivotX, pivotY := pivotPoint(component, float64(width), float64(height))
+
+	if isBlended(component.Blend) {
+		// Blend modes need the component on its own layer first, so the transformed pixels can be mixed with the output
+		layerCtx := gg.NewContext(outputCtx.Width(), outputCtx.Height())
+		layerCtx.RotateAbout(angle, x+pivotX, y+pivotY)
+		layerCtx.DrawImage(frameImage, int(x), int(y))
+		blendImage(outputCtx.Image().(*image.RGBA), layerCtx.Image().(*image.RGBA), component.Blend)
+		return
+	}
+
+	outputCtx.Push()
+	outputCtx.RotateAbout(angle, x+pivotX, y+pivotY)
+	outputCtx.DrawImage(frameImage, int(x), int(y))
+	outputCtx.Pop()
 }
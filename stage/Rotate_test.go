@@ -0,0 +1,61 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"math"
+	"testing"
+)
+
+func roundBounds(minX, minY, maxX, maxY float64) []float64 {
+	return []float64{math.Round(minX), math.Round(minY), math.Round(maxX), math.Round(maxY)}
+}
+
+func TestComponentBounds(t *testing.T) {
+	component := &entity.ImageComponent{
+		Position:     entity.Position{X: float64(10), Y: float64(10), Width: float64(40), Height: float64(20)},
+		Rotation:     90,
+		RotationUnit: "deg",
+	}
+	// Rotating about the centre keeps the centre at 30,20
+	assert.Equal(t, []float64{20, 0, 40, 40}, roundBounds(componentBounds(component)))
+
+	component.Pivot = &entity.Point{X: float64(0), Y: float64(0)}
+	assert.Equal(t, []float64{-10, 10, 10, 50}, roundBounds(componentBounds(component)))
+
+	component.Pivot = &entity.Point{X: "100%", Y: "100%"}
+	assert.Equal(t, []float64{50, -10, 70, 30}, roundBounds(componentBounds(component)))
+}
+
+func TestExpandCanvas(t *testing.T) {
+	request := &entity.ImageRequest{
+		Width:  100,
+		Height: 100,
+		Expand: true,
+		ImageComponents: []*entity.ImageComponent{
+			{Position: entity.Position{X: float64(-20), Y: float64(50), Width: float64(40), Height: float64(80)}},
+		},
+	}
+	ExpandCanvas(request)
+	assert.Equal(t, 120, request.Width)
+	assert.Equal(t, 130, request.Height)
+	assert.Equal(t, float64(0), request.ImageComponents[0].Position.X)
+	assert.Equal(t, float64(50), request.ImageComponents[0].Position.Y)
+}
+
+func TestExpandCanvasMovesTransforms(t *testing.T) {
+	matrix := &entity.ImageComponent{
+		Position:  entity.Position{Width: float64(10), Height: float64(10)},
+		Transform: &entity.Transform{Matrix: []float64{1, 0, 0, 1, -30, 0}},
+	}
+	corners := &entity.ImageComponent{
+		Position:  entity.Position{Width: float64(10), Height: float64(10)},
+		Transform: &entity.Transform{Corners: [][]float64{{0, -10}, {10, -10}, {10, 0}, {0, 0}}},
+	}
+	request := &entity.ImageRequest{Width: 50, Height: 50, Expand: true, ImageComponents: []*entity.ImageComponent{matrix, corners}}
+	ExpandCanvas(request)
+	assert.Equal(t, 80, request.Width)
+	assert.Equal(t, 60, request.Height)
+	assert.Equal(t, []float64{1, 0, 0, 1, 0, 10}, matrix.Transform.Matrix)
+	assert.Equal(t, [][]float64{{30, 0}, {40, 0}, {40, 10}, {30, 10}}, corners.Transform.Corners)
+}
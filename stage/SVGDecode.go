@@ -0,0 +1,74 @@
+package stage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"log"
+	"math"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// The largest an SVG is rasterised at on either side, larger components scale the raster up instead
+const _maxSVGSize = 4096
+
+// isSVG sniffs the start of the body for an XML declaration or svg tag, as SVG has no magic number
+func isSVG(body []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("<svg")) {
+		return true
+	}
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<!DOCTYPE svg")) {
+		header := trimmed
+		if len(header) > 1024 {
+			header = header[:1024]
+		}
+		return bytes.Contains(header, []byte("<svg"))
+	}
+	return false
+}
+
+// decodeSVG rasterises an SVG at the given size, so it stays crisp however large the component is.
+// If only one dimension is given the other is worked out from the SVG's aspect ratio,
+// and if neither is given the SVG's own size is used. The size is capped at _maxSVGSize, keeping the aspect ratio
+func decodeSVG(body []byte, width float64, height float64) ([]*image.Image, []int, error) {
+	log.Println("Rasterising the svg...")
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(body), oksvg.WarnErrorMode)
+	if err != nil {
+		log.Printf("Error parsing svg: %s\n", err)
+		return nil, nil, err
+	}
+
+	viewWidth := icon.ViewBox.W
+	viewHeight := icon.ViewBox.H
+	if viewWidth <= 0 || viewHeight <= 0 {
+		return nil, nil, errors.New("svg: missing width, height or viewBox")
+	}
+
+	switch {
+	case width <= 0 && height <= 0:
+		width = viewWidth
+		height = viewHeight
+	case width <= 0:
+		width = height * viewWidth / viewHeight
+	case height <= 0:
+		height = width * viewHeight / viewWidth
+	}
+	if largest := math.Max(width, height); largest > _maxSVGSize {
+		width = width * _maxSVGSize / largest
+		height = height * _maxSVGSize / largest
+	}
+
+	pixelWidth := int(math.Ceil(width))
+	pixelHeight := int(math.Ceil(height))
+	icon.SetTarget(0, 0, width, height)
+
+	output := image.NewRGBA(image.Rect(0, 0, pixelWidth, pixelHeight))
+	scanner := rasterx.NewScannerGV(pixelWidth, pixelHeight, output, output.Bounds())
+	icon.Draw(rasterx.NewDasher(pixelWidth, pixelHeight, scanner), 1)
+
+	genericImage := image.Image(output)
+	return []*image.Image{&genericImage}, []int{}, nil
+}
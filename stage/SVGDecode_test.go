@@ -0,0 +1,51 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"net/url"
+	"testing"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 20"><rect x="0" y="0" width="5" height="20" fill="#ff0000"/></svg>`
+
+func TestInlineSVGScalesToPosition(t *testing.T) {
+	component := &entity.ImageComponent{URL: testSVG, Position: entity.Position{Width: float64(100)}}
+	frames, _, err := getInlineImage(component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 100, 200), (*frames[0]).Bounds())
+	assertPixel(t, frames[0], 25, 100, red)
+	assertPixel(t, frames[0], 75, 100, transparent)
+}
+
+func TestDataURLSVG(t *testing.T) {
+	assert.True(t, isInlineImage("data:image/svg+xml,"+url.PathEscape(testSVG)))
+	component := &entity.ImageComponent{URL: "data:image/svg+xml," + url.PathEscape(testSVG)}
+	frames, _, err := getInlineImage(component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 10, 20), (*frames[0]).Bounds())
+}
+
+func TestSVGRelativeSize(t *testing.T) {
+	component := &entity.ImageComponent{URL: testSVG, Position: entity.Position{Height: "50%"}}
+	request := &entity.ImageRequest{Width: 100, Height: 400, ImageComponents: []*entity.ImageComponent{component}}
+	_, componentFrameImages, err := MapComponentFrames(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 100, 200), (*componentFrameImages[0][0]).Bounds())
+}
+
+func TestSVGSizeLimit(t *testing.T) {
+	component := &entity.ImageComponent{URL: testSVG, Position: entity.Position{Width: float64(100000)}}
+	frames, _, err := getInlineImage(component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, _maxSVGSize/2, _maxSVGSize), (*frames[0]).Bounds())
+}
@@ -0,0 +1,222 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"golang.org/x/image/draw"
+	"image"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// The delay of each frame of a transition, in centiseconds
+const _transitionDelay = 5
+
+// slideFrames are the frames of a slide, already fitted to the slideshow
+type slideFrames struct {
+	slide  *entity.Slide
+	frames []*image.RGBA
+	delays []int
+}
+
+// renderSlideshow shows each slide for its hold time with a transition between them, looping back to the first
+// slide unless the component only plays once. Without a size, the slideshow is the size of the first slide
+func renderSlideshow(request *entity.ImageRequest, component *entity.ImageComponent) ([]*image.Image, []int, error) {
+	if len(component.Slides) == 0 {
+		log.Println("Slideshow has no slides")
+		return nil, nil, nil
+	}
+
+	loaded := make([][]*image.Image, len(component.Slides))
+	loadedDelays := make([][]int, len(component.Slides))
+	for i, slide := range component.Slides {
+		frames, delays, exception := getComponentImage(&entity.ImageComponent{URL: slide.URL, Local: slide.Local})
+		if exception != nil {
+			return nil, nil, exception
+		}
+		loaded[i] = frames
+		loadedDelays[i] = delays
+	}
+
+	width := relativeValue(component.Position.Width, request.Width, 0)
+	height := relativeValue(component.Position.Height, request.Height, 0)
+	firstBounds := (*loaded[0][0]).Bounds()
+	if width == 0 && height == 0 {
+		width, height = firstBounds.Dx(), firstBounds.Dy()
+	} else if width == 0 {
+		width = height * firstBounds.Dx() / firstBounds.Dy()
+	} else if height == 0 {
+		height = width * firstBounds.Dy() / firstBounds.Dx()
+	}
+
+	slides := make([]*slideFrames, len(component.Slides))
+	for i, slide := range component.Slides {
+		fit := slide.Fit
+		if fit == "" {
+			fit = "contain"
+		}
+		slides[i] = &slideFrames{slide: slide, delays: loadedDelays[i], frames: make([]*image.RGBA, len(loaded[i]))}
+		for f, frame := range loaded[i] {
+			slides[i].frames[f] = fitFrame(*frame, width, height, fit, component.Anchor, component.Resample)
+		}
+	}
+
+	// Long holds and transitions are cut off at the most frames the output can have
+	maxFrames := requestMaxFrames(request)
+	outputImages := make([]*image.Image, 0)
+	outputDelay := make([]int, 0)
+	addFrame := func(frame *image.RGBA, delay int) bool {
+		if len(outputImages) >= maxFrames {
+			return false
+		}
+		genericImage := image.Image(frame)
+		outputImages = append(outputImages, &genericImage)
+		outputDelay = append(outputDelay, delay)
+		return true
+	}
+
+	// Times given as a number of frames are frames of the request's default delay
+	frameLength := helper.GetRequestDelay(request)
+	loop := component.Playback != "once" && component.Playback != "hold"
+	for i, current := range slides {
+		holdSlide(current, frameLength, addFrame)
+
+		if i == len(slides)-1 && (!loop || len(slides) == 1) {
+			break
+		}
+		next := slides[(i+1)%len(slides)]
+		from := current.frames[len(current.frames)-1]
+		to := next.frames[0]
+		duration := helper.GetCentiseconds(next.slide.TransitionDuration, frameLength, 50)
+		steps := int(math.Min(float64(duration/_transitionDelay), float64(maxFrames)))
+		if next.slide.Transition == "none" || steps < 1 {
+			continue
+		}
+		for step := 0; step < steps && len(outputImages) < maxFrames; step++ {
+			progress := (float64(step) + 0.5) / float64(steps)
+			addFrame(transitionFrame(next.slide.Transition, from, to, progress), _transitionDelay)
+		}
+	}
+
+	// A single frame is a still image, which shouldn't get a delay
+	if len(outputImages) == 1 {
+		return outputImages, []int{}, nil
+	}
+	return outputImages, outputDelay, nil
+}
+
+// holdSlide adds the frames shown while a slide is held, looping an animation or cutting it short to fit.
+// Holds are at most _maxLoopDuration, and stop early once addFrame has no room for more frames
+func holdSlide(slide *slideFrames, frameLength int, addFrame func(frame *image.RGBA, delay int) bool) {
+	if len(slide.frames) == 1 {
+		hold := helper.GetCentiseconds(slide.slide.Hold, frameLength, 200)
+		addFrame(slide.frames[0], int(math.Min(float64(hold), _maxLoopDuration)))
+		return
+	}
+
+	frameDelay := func(f int) int {
+		if f < len(slide.delays) && slide.delays[f] > 0 {
+			return slide.delays[f]
+		}
+		return frameLength
+	}
+	playLength := 0
+	for f := range slide.frames {
+		playLength += frameDelay(f)
+	}
+
+	hold := int(math.Min(float64(helper.GetCentiseconds(slide.slide.Hold, frameLength, playLength)), _maxLoopDuration))
+	for shown, f := 0, 0; shown < hold; f++ {
+		delay := int(math.Min(float64(frameDelay(f%len(slide.frames))), float64(hold-shown)))
+		if !addFrame(slide.frames[f%len(slide.frames)], delay) {
+			return
+		}
+		shown += delay
+	}
+}
+
+// transitionFrame draws a frame part way through a transition from one slide to the next
+func transitionFrame(transition string, from *image.RGBA, to *image.RGBA, progress float64) *image.RGBA {
+	output := image.NewRGBA(from.Rect)
+	width := float64(from.Rect.Dx())
+	height := float64(from.Rect.Dy())
+
+	kind, direction := transition, "left"
+	if separator := strings.Index(transition, "-"); separator != -1 {
+		kind, direction = transition[:separator], transition[separator+1:]
+	}
+
+	switch kind {
+	case "slide":
+		// The next slide pushes the current one out of the way
+		offsetX, offsetY := slideOffset(direction, width, height)
+		fromOffset := image.Pt(int(math.Round(-offsetX*progress)), int(math.Round(-offsetY*progress)))
+		toOffset := image.Pt(int(math.Round(offsetX*(1-progress))), int(math.Round(offsetY*(1-progress))))
+		draw.Draw(output, output.Rect.Add(fromOffset), from, from.Rect.Min, draw.Src)
+		draw.Draw(output, output.Rect.Add(toOffset), to, to.Rect.Min, draw.Src)
+	case "wipe":
+		// The edge of the next slide moves across, uncovering it
+		draw.Draw(output, output.Rect, from, from.Rect.Min, draw.Src)
+		draw.Draw(output, wipeRect(direction, output.Rect, progress), to, to.Rect.Min, draw.Src)
+	case "zoom":
+		// The next slide grows from the middle
+		draw.Draw(output, output.Rect, from, from.Rect.Min, draw.Src)
+		zoomWidth := width * progress
+		zoomHeight := height * progress
+		target := image.Rect(
+			int(math.Round((width-zoomWidth)/2)), int(math.Round((height-zoomHeight)/2)),
+			int(math.Round((width+zoomWidth)/2)), int(math.Round((height+zoomHeight)/2)),
+		)
+		if !target.Empty() {
+			draw.BiLinear.Scale(output, target, to, to.Rect, draw.Over, nil)
+		}
+	case "dissolve":
+		// Each pixel switches at its own random point, the same for every frame
+		random := rand.New(rand.NewSource(1))
+		for i := 0; i < len(output.Pix); i += 4 {
+			source := from
+			if random.Float64() < progress {
+				source = to
+			}
+			copy(output.Pix[i:i+4], source.Pix[i:i+4])
+		}
+	default:
+		if kind != "crossfade" && kind != "" {
+			log.Println("Unknown transition", transition)
+		}
+		for i := range output.Pix {
+			output.Pix[i] = uint8(float64(from.Pix[i])*(1-progress) + float64(to.Pix[i])*progress + 0.5)
+		}
+	}
+	return output
+}
+
+// slideOffset gets where the next slide starts from for a slide transition moving in a direction
+func slideOffset(direction string, width float64, height float64) (float64, float64) {
+	switch direction {
+	case "right":
+		return -width, 0
+	case "up":
+		return 0, height
+	case "down":
+		return 0, -height
+	}
+	return width, 0
+}
+
+// wipeRect gets the part of the next slide that has been uncovered by a wipe moving in a direction
+func wipeRect(direction string, rect image.Rectangle, progress float64) image.Rectangle {
+	width := int(math.Round(float64(rect.Dx()) * progress))
+	height := int(math.Round(float64(rect.Dy()) * progress))
+	switch direction {
+	case "right":
+		return image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+width, rect.Max.Y)
+	case "up":
+		return image.Rect(rect.Min.X, rect.Max.Y-height, rect.Max.X, rect.Max.Y)
+	case "down":
+		return image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+height)
+	}
+	return image.Rect(rect.Max.X-width, rect.Min.Y, rect.Max.X, rect.Max.Y)
+}
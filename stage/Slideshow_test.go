@@ -0,0 +1,73 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+func TestSlideshowFrames(t *testing.T) {
+	component := &entity.ImageComponent{
+		Type: "slideshow",
+		Slides: []*entity.Slide{
+			{URL: squareSVG(10, "#ff0000"), Hold: "100ms", TransitionDuration: "100ms"},
+			{URL: squareSVG(20, "#0000ff"), Hold: float64(2), TransitionDuration: "100ms", Transition: "wipe-right"},
+		},
+	}
+	frames, delays, err := renderSlideshow(&entity.ImageRequest{}, component)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []int{10, 5, 5, 20, 5, 5}, delays)
+	// Sized from the first slide, with the second fitted into it
+	assert.Equal(t, image.Rect(0, 0, 10, 10), (*frames[3]).Bounds())
+	assertPixel(t, frames[0], 5, 5, red)
+	assertPixel(t, frames[3], 5, 5, blue)
+	// Wiping to blue from the left, then crossfading back to red
+	assertPixel(t, frames[1], 1, 5, blue)
+	assertPixel(t, frames[1], 8, 5, red)
+	r, _, b, _ := (*frames[4]).At(5, 5).RGBA()
+	assert.Less(t, r, b)
+}
+
+func TestSlideshowLimits(t *testing.T) {
+	component := &entity.ImageComponent{
+		Type: "slideshow",
+		Slides: []*entity.Slide{
+			{URL: squareSVG(10, "#ff0000"), Hold: "100000000ms"},
+			{URL: squareSVG(10, "#0000ff"), TransitionDuration: "100000000ms"},
+		},
+	}
+	frames, delays, err := renderSlideshow(&entity.ImageRequest{MaxFrames: 20}, component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, frames, 20)
+	assert.Equal(t, _maxLoopDuration, delays[0])
+}
+
+func TestSlideTransition(t *testing.T) {
+	from := solidFrame(10, 10, red)
+	to := solidFrame(10, 10, blue)
+
+	pushed := transitionFrame("slide-up", from, to, 0.5)
+	assert.Equal(t, red, pushed.RGBAAt(5, 2))
+	assert.Equal(t, blue, pushed.RGBAAt(5, 7))
+
+	zoomed := transitionFrame("zoom", from, to, 0.5)
+	assert.Equal(t, red, zoomed.RGBAAt(1, 1))
+	assert.Equal(t, blue, zoomed.RGBAAt(5, 5))
+
+	dissolved := transitionFrame("dissolve", from, to, 0.5)
+	blueCount := 0
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if dissolved.RGBAAt(x, y) == blue {
+				blueCount++
+			}
+		}
+	}
+	assert.InDelta(t, 50, blueCount, 15)
+}
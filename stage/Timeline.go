@@ -0,0 +1,261 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/helper"
+	"image"
+	"log"
+	"sort"
+)
+
+const _defaultMaxFrames = 200
+
+// The longest an output will be stretched to so that every looping component finishes a whole loop, in centiseconds
+const _maxLoopDuration = 6000
+
+// componentClock places the frames of a component on the timeline
+type componentClock struct {
+	// When each frame starts, in centiseconds from when the component first appears
+	starts   []int
+	duration int
+	// How many times the frames play, 0 keeps looping
+	plays int
+	hold  bool
+	// When the component appears and disappears, end is -1 if it never does
+	start int
+	end   int
+	// The delay of frames without one
+	defaultDelay int
+}
+
+// timeline is the set of output frames, with when each one starts and how long it's shown for
+type timeline struct {
+	times    []int
+	delays   []int
+	duration int
+	clocks   []*componentClock
+	// The clocks of animated masks, which play alongside their component
+	masks []*componentClock
+}
+
+// newComponentClock works out when each frame of a component starts, or returns nil if it's shown the whole time
+func newComponentClock(component *entity.ImageComponent, frameDelay []int, frameCount int, defaultDelay int) *componentClock {
+	clock := &componentClock{
+		plays:        component.Loop,
+		hold:         component.Playback == "hold",
+		start:        helper.GetCentiseconds(component.Start, defaultDelay, 0),
+		end:          helper.GetCentiseconds(component.End, defaultDelay, -1),
+		defaultDelay: defaultDelay,
+	}
+	if (component.Playback == "hold" || component.Playback == "once") && clock.plays == 0 {
+		clock.plays = 1
+	}
+
+	if frameCount < 2 {
+		if clock.start == 0 && clock.end < 0 {
+			return nil
+		}
+		clock.starts = []int{0}
+		return clock
+	}
+
+	clock.starts = make([]int, frameCount)
+	for i := range clock.starts {
+		clock.starts[i] = clock.duration
+		delay := defaultDelay
+		// Like browsers, a delay of 0 is treated as the default
+		if i < len(frameDelay) && frameDelay[i] > 0 {
+			delay = frameDelay[i]
+		}
+		clock.duration += delay
+	}
+	return clock
+}
+
+// frameAt gets which frame of the component is shown at a time, or -1 if it isn't shown at all
+func (c *componentClock) frameAt(time int) int {
+	if c == nil {
+		return 0
+	}
+	if time < c.start || (c.end >= 0 && time >= c.end) {
+		return -1
+	}
+	if c.duration == 0 {
+		return 0
+	}
+	time -= c.start
+	if c.plays > 0 && time >= c.duration*c.plays {
+		if c.hold {
+			return len(c.starts) - 1
+		}
+		return -1
+	}
+	time %= c.duration
+	return sort.Search(len(c.starts), func(i int) bool { return c.starts[i] > time }) - 1
+}
+
+// extent gets when the component finishes doing anything, which the output should last until
+func (c *componentClock) extent() int {
+	if c.end >= 0 {
+		return c.end
+	}
+	if c.duration == 0 {
+		// Something that appears part way through should be seen for at least a frame
+		if c.start > 0 {
+			return c.start + c.defaultDelay
+		}
+		return 0
+	}
+	plays := c.plays
+	if plays == 0 {
+		plays = 1
+	}
+	return c.start + c.duration*plays
+}
+
+// changePoints gets every time before the end of the output that the component changes frame
+func (c *componentClock) changePoints(duration int) []int {
+	points := []int{c.start}
+	if c.end >= 0 {
+		points = append(points, c.end)
+	}
+	if c.duration > 0 {
+		for play := 0; c.plays == 0 || play < c.plays; play++ {
+			playStart := c.start + play*c.duration
+			if playStart >= duration {
+				break
+			}
+			for _, start := range c.starts {
+				points = append(points, playStart+start)
+			}
+		}
+		// Disappearing after the last play is one last change
+		if c.plays > 0 && !c.hold {
+			points = append(points, c.start+c.duration*c.plays)
+		}
+	}
+
+	inRange := make([]int, 0, len(points))
+	for _, point := range points {
+		if point < duration {
+			inRange = append(inRange, point)
+		}
+	}
+	return inRange
+}
+
+// requestMaxFrames gets the most frames the output can have
+func requestMaxFrames(request *entity.ImageRequest) int {
+	if request.MaxFrames <= 0 {
+		return _defaultMaxFrames
+	}
+	return request.MaxFrames
+}
+
+// buildTimeline places every component on a common clock. The output is long enough for each looping component to
+// loop a whole number of times if possible, and has a frame for every point that any component changes frame
+func buildTimeline(request *entity.ImageRequest, componentFrameDelays [][]int, componentFrameImages [][]*image.Image, masks []*maskAnimation) *timeline {
+	output := &timeline{
+		clocks: make([]*componentClock, len(request.ImageComponents)),
+		masks:  make([]*componentClock, len(request.ImageComponents)),
+	}
+	maxFrames := requestMaxFrames(request)
+
+	loopDuration := 1
+	longest := 0
+	for comp, component := range request.ImageComponents {
+		output.clocks[comp] = newComponentClock(component, componentFrameDelays[comp], len(componentFrameImages[comp]), helper.GetRequestDelay(request))
+		// A mask keeps looping for as long as its component is shown
+		if comp < len(masks) && masks[comp] != nil {
+			maskComponent := &entity.ImageComponent{Start: component.Start, End: component.End}
+			output.masks[comp] = newComponentClock(maskComponent, masks[comp].delays, len(masks[comp].frames), helper.GetRequestDelay(request))
+		}
+
+		for _, clock := range []*componentClock{output.clocks[comp], output.masks[comp]} {
+			if clock == nil {
+				continue
+			}
+			if clock.extent() > longest {
+				longest = clock.extent()
+			}
+			// Once the loop is too long to use there's no point making it longer, which could eventually overflow
+			if clock.duration > 0 && clock.plays == 0 && clock.end < 0 && loopDuration <= _maxLoopDuration {
+				loopDuration = lcm(loopDuration, clock.duration)
+			}
+		}
+	}
+
+	// Nothing is animated or timed, so there is a single frame
+	if longest == 0 {
+		output.times = []int{0}
+		output.delays = []int{helper.GetRequestDelay(request)}
+		return output
+	}
+
+	output.duration = longest
+	if loopDuration > longest && loopDuration <= _maxLoopDuration {
+		output.duration = loopDuration
+	}
+	times := output.changePoints()
+	if len(times) > maxFrames && output.duration > longest {
+		log.Println("Too many frames to loop every component, cutting the output to", longest)
+		output.duration = longest
+		times = output.changePoints()
+	}
+	if len(times) > maxFrames {
+		log.Println("Too many frames, sampling", maxFrames, "of", len(times))
+		sampled := make([]int, maxFrames)
+		for i := range sampled {
+			sampled[i] = times[i*len(times)/maxFrames]
+		}
+		times = sampled
+	}
+
+	output.times = times
+	output.delays = make([]int, len(times))
+	for i, time := range times {
+		next := output.duration
+		if i+1 < len(times) {
+			next = times[i+1]
+		}
+		output.delays[i] = next - time
+	}
+	return output
+}
+
+// changePoints gets the sorted, unique times at which any component changes frame
+func (t *timeline) changePoints() []int {
+	unique := map[int]bool{0: true}
+	for _, clock := range append(append([]*componentClock{}, t.clocks...), t.masks...) {
+		if clock == nil {
+			continue
+		}
+		for _, point := range clock.changePoints(t.duration) {
+			unique[point] = true
+		}
+	}
+	times := make([]int, 0, len(unique))
+	for time := range unique {
+		times = append(times, time)
+	}
+	sort.Ints(times)
+	return times
+}
+
+// frameAt gets which frame of a component is shown on an output frame, or -1 if it isn't shown
+func (t *timeline) frameAt(comp int, outputFrame int) int {
+	return t.clocks[comp].frameAt(t.times[outputFrame])
+}
+
+// maskAt gets which frame of a component's mask image is shown on an output frame, or -1 if it isn't shown
+func (t *timeline) maskAt(comp int, outputFrame int) int {
+	return t.masks[comp].frameAt(t.times[outputFrame])
+}
+
+func lcm(a int, b int) int {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
@@ -0,0 +1,88 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+func TestTimelineLoopsEveryComponent(t *testing.T) {
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{{}, {}, {}}}
+	output := buildTimeline(request, [][]int{{}, {10, 10}, {10, 20}}, [][]*image.Image{blankFrames(1), blankFrames(2), blankFrames(2)}, nil)
+
+	// Loops of 20 and 30 line up after 60
+	assert.Equal(t, []int{0, 10, 20, 30, 40, 50}, output.times)
+	assert.Equal(t, []int{10, 10, 10, 10, 10, 10}, output.delays)
+	assert.Equal(t, 0, output.frameAt(0, 3))
+	assert.Equal(t, 1, output.frameAt(1, 3))
+	assert.Equal(t, []int{0, 1, 1, 0, 1, 1}, []int{output.frameAt(2, 0), output.frameAt(2, 1), output.frameAt(2, 2), output.frameAt(2, 3), output.frameAt(2, 4), output.frameAt(2, 5)})
+}
+
+func TestTimelinePlayback(t *testing.T) {
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{{}, {Playback: "hold"}, {Playback: "once"}}}
+	output := buildTimeline(request, [][]int{repeatDelay(10, 6), {5, 5}, {15}}, [][]*image.Image{blankFrames(6), blankFrames(2), blankFrames(2)}, nil)
+
+	assert.Equal(t, 60, output.duration)
+	assert.Equal(t, []int{0, 5, 10, 15, 20, 25, 30, 40, 50}, output.times)
+	// Held on the last frame
+	assert.Equal(t, 1, output.frameAt(1, 8))
+	// Played once then gone
+	assert.Equal(t, 1, output.frameAt(2, 3))
+	assert.Equal(t, -1, output.frameAt(2, 6))
+}
+
+func TestTimelineMaxFrames(t *testing.T) {
+	request := &entity.ImageRequest{MaxFrames: 20, ImageComponents: []*entity.ImageComponent{{}, {}}}
+	output := buildTimeline(request, [][]int{repeatDelay(5, 10), repeatDelay(3, 23)}, [][]*image.Image{blankFrames(10), blankFrames(23)}, nil)
+
+	assert.Len(t, output.times, 20)
+	assert.Equal(t, 69, output.duration)
+	total := 0
+	for _, delay := range output.delays {
+		total += delay
+	}
+	assert.Equal(t, 69, total)
+}
+
+func TestTimelineLongLoop(t *testing.T) {
+	// Together these would loop after more centiseconds than an int can hold
+	primes := []int{1009, 1013, 1019, 1021, 1031, 1033, 1039, 1049, 1051, 1061, 1063, 1069, 1087, 1091, 1093, 1097}
+	request := &entity.ImageRequest{}
+	componentFrameDelays := make([][]int, len(primes))
+	componentFrameImages := make([][]*image.Image, len(primes))
+	for i, prime := range primes {
+		request.ImageComponents = append(request.ImageComponents, &entity.ImageComponent{})
+		componentFrameDelays[i] = []int{prime, prime}
+		componentFrameImages[i] = blankFrames(2)
+	}
+	output := buildTimeline(request, componentFrameDelays, componentFrameImages, nil)
+
+	assert.Equal(t, 2194, output.duration)
+}
+
+func TestStaticTimeline(t *testing.T) {
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{{}}}
+	output := buildTimeline(request, [][]int{{}}, [][]*image.Image{blankFrames(1)}, nil)
+	assert.Equal(t, []int{0}, output.times)
+	assert.Equal(t, 0, output.frameAt(0, 0))
+}
+
+func TestTimelineVisibilityWindows(t *testing.T) {
+	request := &entity.ImageRequest{ImageComponents: []*entity.ImageComponent{
+		{End: "200ms"},
+		{Start: float64(2), Loop: 2},
+		{Start: "300ms"},
+	}}
+	output := buildTimeline(request, [][]int{{}, {5, 5}, {}}, [][]*image.Image{blankFrames(1), blankFrames(2), blankFrames(1)}, nil)
+
+	assert.Equal(t, []int{0, 20, 25, 30, 35}, output.times)
+	assert.Equal(t, []int{20, 5, 5, 5, 5}, output.delays)
+	// Only shown until 200ms
+	assert.Equal(t, []int{0, -1}, []int{output.frameAt(0, 0), output.frameAt(0, 1)})
+	// Plays twice from 200ms, which is the end of the output
+	assert.Equal(t, []int{-1, 0, 1, 0, 1}, []int{output.frameAt(1, 0), output.frameAt(1, 1), output.frameAt(1, 2), output.frameAt(1, 3), output.frameAt(1, 4)})
+	assert.Equal(t, -1, output.clocks[1].frameAt(40))
+	// Appears at 300ms and stays
+	assert.Equal(t, []int{-1, 0, 0}, []int{output.frameAt(2, 2), output.frameAt(2, 3), output.frameAt(2, 4)})
+}
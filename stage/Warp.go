@@ -0,0 +1,203 @@
+package stage
+
+import (
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"log"
+	"math"
+)
+
+// homography is a 3x3 projective transform, stored row by row
+type homography [9]float64
+
+// apply maps a point through the homography
+func (h homography) apply(x float64, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
+
+// bounds gets the bounding box of a width x height rectangle once it's been mapped through the homography
+func (h homography) bounds(width float64, height float64) (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {width, 0}, {width, height}, {0, height}} {
+		x, y := h.apply(corner[0], corner[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// invertible checks the homography has no NaN or infinite coefficients and doesn't squash everything onto a line,
+// which happens when the corners are collinear or overlap
+func (h homography) invertible() bool {
+	for _, coefficient := range h {
+		if math.IsNaN(coefficient) || math.IsInf(coefficient, 0) {
+			return false
+		}
+	}
+	return math.Abs(h.determinant()) >= 1e-12
+}
+
+// determinant gets the determinant of the homography's matrix
+func (h homography) determinant() float64 {
+	return h[0]*(h[4]*h[8]-h[5]*h[7]) - h[1]*(h[3]*h[8]-h[5]*h[6]) + h[2]*(h[3]*h[7]-h[4]*h[6])
+}
+
+// inverse gets the homography going the other way, returning false if it can't be inverted
+func (h homography) inverse() (homography, bool) {
+	if !h.invertible() {
+		return homography{}, false
+	}
+	cofactors := homography{
+		h[4]*h[8] - h[5]*h[7], h[2]*h[7] - h[1]*h[8], h[1]*h[5] - h[2]*h[4],
+		h[5]*h[6] - h[3]*h[8], h[0]*h[8] - h[2]*h[6], h[2]*h[3] - h[0]*h[5],
+		h[3]*h[7] - h[4]*h[6], h[1]*h[6] - h[0]*h[7], h[0]*h[4] - h[1]*h[3],
+	}
+	determinant := h.determinant()
+	for i := range cofactors {
+		cofactors[i] /= determinant
+	}
+	return cofactors, true
+}
+
+// squareToQuad builds the homography mapping a width x height rectangle onto four corners (clockwise from top left)
+func squareToQuad(width float64, height float64, corners [][]float64) homography {
+	x0, y0 := corners[0][0], corners[0][1]
+	x1, y1 := corners[1][0], corners[1][1]
+	x2, y2 := corners[2][0], corners[2][1]
+	x3, y3 := corners[3][0], corners[3][1]
+
+	var h homography
+	sumX := x0 - x1 + x2 - x3
+	sumY := y0 - y1 + y2 - y3
+	if sumX == 0 && sumY == 0 {
+		// A parallelogram is just an affine transform
+		h = homography{x1 - x0, x2 - x1, x0, y1 - y0, y2 - y1, y0, 0, 0, 1}
+	} else {
+		dx1, dx2 := x1-x2, x3-x2
+		dy1, dy2 := y1-y2, y3-y2
+		denominator := dx1*dy2 - dx2*dy1
+		g := (sumX*dy2 - dx2*sumY) / denominator
+		k := (dx1*sumY - sumX*dy1) / denominator
+		h = homography{x1 - x0 + g*x1, x3 - x0 + k*x3, x0, y1 - y0 + g*y1, y3 - y0 + k*y3, y0, g, k, 1}
+	}
+
+	// The above maps the unit square, so scale the component down to that first
+	h[0] /= width
+	h[3] /= width
+	h[6] /= width
+	h[1] /= height
+	h[4] /= height
+	h[7] /= height
+	return h
+}
+
+// transformHomography gets the homography from component pixels to the canvas, or false if the transform is invalid,
+// including when it would flatten the component onto a line or point
+func transformHomography(transform *entity.Transform, width float64, height float64) (homography, bool) {
+	var h homography
+	if len(transform.Corners) == 4 {
+		for _, corner := range transform.Corners {
+			if len(corner) < 2 {
+				return homography{}, false
+			}
+		}
+		h = squareToQuad(width, height, transform.Corners)
+	} else if len(transform.Matrix) == 6 {
+		m := transform.Matrix
+		h = homography{m[0], m[2], m[4], m[1], m[3], m[5], 0, 0, 1}
+	} else {
+		return homography{}, false
+	}
+	if !h.invertible() {
+		return homography{}, false
+	}
+	return h, true
+}
+
+// sampleBilinear reads a premultiplied colour at a point in the source, fading to transparent past its edges
+func sampleBilinear(source *image.RGBA, x float64, y float64) [4]float64 {
+	var output [4]float64
+	// Pixel centres are at .5, so shift to make the maths work in whole pixels
+	x -= 0.5
+	y -= 0.5
+	left := int(math.Floor(x))
+	top := int(math.Floor(y))
+	fractionX := x - float64(left)
+	fractionY := y - float64(top)
+	width := source.Rect.Dx()
+	height := source.Rect.Dy()
+
+	for dy := 0; dy < 2; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			px := left + dx
+			py := top + dy
+			if px < 0 || py < 0 || px >= width || py >= height {
+				continue
+			}
+			weight := math.Abs(float64(1-dx)-fractionX) * math.Abs(float64(1-dy)-fractionY)
+			offset := source.PixOffset(source.Rect.Min.X+px, source.Rect.Min.Y+py)
+			for c := 0; c < 4; c++ {
+				output[c] += float64(source.Pix[offset+c]) * weight
+			}
+		}
+	}
+	return output
+}
+
+// warpFrame maps a frame through its transform onto a transparent layer the size of the canvas,
+// using the inverse mapping so every output pixel is filled, and 4 samples per pixel to smooth the edges.
+// Returns the layer and the area of it that was drawn to
+func warpFrame(frameImage *image.RGBA, transform *entity.Transform, canvasWidth int, canvasHeight int) (*image.RGBA, image.Rectangle) {
+	layer := image.NewRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+	width := float64(frameImage.Rect.Dx())
+	height := float64(frameImage.Rect.Dy())
+
+	forward, ok := transformHomography(transform, width, height)
+	if !ok {
+		log.Println("Invalid transform", transform)
+		return layer, image.Rectangle{}
+	}
+	inverse, ok := forward.inverse()
+	if !ok {
+		log.Println("Transform can't be inverted", transform)
+		return layer, image.Rectangle{}
+	}
+
+	// Only the bounding box of the transformed corners needs to be looked at
+	minX, minY, maxX, maxY := forward.bounds(width, height)
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(layer.Rect)
+
+	subSamples := [][2]float64{{0.25, 0.25}, {0.75, 0.25}, {0.25, 0.75}, {0.75, 0.75}}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var total [4]float64
+			for _, subSample := range subSamples {
+				sourceX, sourceY := inverse.apply(float64(x)+subSample[0], float64(y)+subSample[1])
+				if sourceX < -1 || sourceY < -1 || sourceX > width+1 || sourceY > height+1 {
+					continue
+				}
+				sample := sampleBilinear(frameImage, sourceX, sourceY)
+				for c := range total {
+					total[c] += sample[c]
+				}
+			}
+			offset := layer.PixOffset(x, y)
+			for c := range total {
+				layer.Pix[offset+c] = uint8(math.Round(total[c] / float64(len(subSamples))))
+			}
+		}
+	}
+	return layer, bounds
+}
+
+// transformedBounds gets the bounding box of a component once it has been through its transform
+func transformedBounds(transform *entity.Transform, width float64, height float64) (float64, float64, float64, float64, bool) {
+	forward, ok := transformHomography(transform, width, height)
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	minX, minY, maxX, maxY := forward.bounds(width, height)
+	return minX, minY, maxX, maxY, true
+}
@@ -0,0 +1,60 @@
+package stage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gl.ocelotworks.com/ocelotbotv5/image-renderer/entity"
+	"image"
+	"testing"
+)
+
+func TestSquareToQuadMapsCorners(t *testing.T) {
+	corners := [][]float64{{10, 20}, {90, 5}, {100, 80}, {0, 70}}
+	h := squareToQuad(40, 30, corners)
+	for i, source := range [][2]float64{{0, 0}, {40, 0}, {40, 30}, {0, 30}} {
+		x, y := h.apply(source[0], source[1])
+		assert.InDelta(t, corners[i][0], x, 1e-9)
+		assert.InDelta(t, corners[i][1], y, 1e-9)
+	}
+
+	inverse, ok := h.inverse()
+	assert.True(t, ok)
+	x, y := inverse.apply(h.apply(12, 7))
+	assert.InDelta(t, 12, x, 1e-9)
+	assert.InDelta(t, 7, y, 1e-9)
+}
+
+func TestWarpFrame(t *testing.T) {
+	frame := solidFrame(20, 20, red)
+	// A trapezoid narrowing towards the top
+	transform := &entity.Transform{Corners: [][]float64{{40, 10}, {60, 10}, {90, 90}, {10, 90}}}
+	layer, bounds := warpFrame(frame, transform, 100, 100)
+	assert.Equal(t, image.Rect(10, 10, 90, 90), bounds)
+	assert.Equal(t, red, layer.RGBAAt(50, 50))
+	assert.Equal(t, transparent, layer.RGBAAt(15, 15))
+	assert.Equal(t, transparent, layer.RGBAAt(85, 15))
+	// The edges are antialiased rather than hard
+	edge := layer.RGBAAt(25, 50).A
+	assert.True(t, edge > 0 && edge < 255, "edge alpha %d", edge)
+
+	minX, minY, maxX, maxY, ok := transformedBounds(&entity.Transform{Matrix: []float64{2, 0, 0, 2, 5, 5}}, 20, 20)
+	assert.True(t, ok)
+	assert.Equal(t, []float64{5, 5, 45, 45}, []float64{minX, minY, maxX, maxY})
+}
+
+func TestDegenerateTransform(t *testing.T) {
+	for _, transform := range []*entity.Transform{
+		// Every corner on one line
+		{Corners: [][]float64{{0, 0}, {10, 10}, {20, 20}, {30, 30}}},
+		// Three corners in the same place
+		{Corners: [][]float64{{0, 0}, {0, 0}, {0, 0}, {10, 10}}},
+		{Matrix: []float64{1, 2, 2, 4, 0, 0}},
+	} {
+		_, ok := transformHomography(transform, 20, 20)
+		assert.False(t, ok, transform)
+		_, _, _, _, ok = transformedBounds(transform, 20, 20)
+		assert.False(t, ok, transform)
+		layer, bounds := warpFrame(solidFrame(20, 20, red), transform, 50, 50)
+		assert.True(t, bounds.Empty())
+		assert.Equal(t, transparent, layer.RGBAAt(10, 10))
+	}
+}
@@ -0,0 +1,176 @@
+package stage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"log"
+
+	"golang.org/x/image/webp"
+)
+
+// Flags from the VP8X and ANMF chunks
+const (
+	webpAnimationFlag  = 1 << 1
+	webpAlphaFlag      = 1 << 4
+	webpDisposeFlag    = 1 << 0
+	webpNoBlendFlag    = 1 << 1
+	webpANMFHeaderSize = 16
+)
+
+type riffChunk struct {
+	fourCC string
+	data   []byte
+}
+
+// readRIFFChunks splits the body of a RIFF container into its chunks
+func readRIFFChunks(body []byte) ([]riffChunk, error) {
+	chunks := make([]riffChunk, 0)
+	offset := 0
+	for offset+8 <= len(body) {
+		fourCC := string(body[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(body[offset+4 : offset+8]))
+		if length < 0 || offset+8+length > len(body) {
+			return nil, errors.New("webp: truncated chunk " + fourCC)
+		}
+		chunks = append(chunks, riffChunk{fourCC: fourCC, data: body[offset+8 : offset+8+length]})
+		// Chunks are padded to an even length
+		offset += 8 + length + length&1
+	}
+	return chunks, nil
+}
+
+// readWebPChunks reads the chunks inside a RIFF WEBP file
+func readWebPChunks(body []byte) ([]riffChunk, error) {
+	if len(body) < 12 || string(body[0:4]) != "RIFF" || string(body[8:12]) != "WEBP" {
+		return nil, errors.New("webp: invalid header")
+	}
+	return readRIFFChunks(body[12:])
+}
+
+// isAnimatedWebP checks the VP8X chunk for the animation flag
+func isAnimatedWebP(body []byte) bool {
+	chunks, err := readWebPChunks(body)
+	if err != nil || len(chunks) == 0 {
+		return false
+	}
+	return chunks[0].fourCC == "VP8X" && len(chunks[0].data) >= 10 && chunks[0].data[0]&webpAnimationFlag != 0
+}
+
+// decodeAnimatedWebP decodes every ANMF frame of an animated WebP and composites them onto the canvas
+func decodeAnimatedWebP(body []byte) ([]*image.Image, []int, error) {
+	log.Println("Decoding the animated webp...")
+	chunks, err := readWebPChunks(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(chunks) == 0 || chunks[0].fourCC != "VP8X" || len(chunks[0].data) < 10 {
+		return nil, nil, errors.New("webp: missing VP8X chunk")
+	}
+
+	canvasWidth := int(readUint24(chunks[0].data[4:])) + 1
+	canvasHeight := int(readUint24(chunks[0].data[7:])) + 1
+	if err := checkCanvasSize("webp", canvasWidth, canvasHeight); err != nil {
+		return nil, nil, err
+	}
+	compositor := newFrameCompositor(canvasWidth, canvasHeight)
+
+	output := make([]*image.Image, 0)
+	delays := make([]int, 0)
+	for _, chunk := range chunks {
+		if chunk.fourCC != "ANMF" {
+			continue
+		}
+		if len(chunk.data) < webpANMFHeaderSize {
+			return nil, nil, errors.New("webp: invalid ANMF chunk")
+		}
+		x := int(readUint24(chunk.data[0:])) * 2
+		y := int(readUint24(chunk.data[3:])) * 2
+		width := int(readUint24(chunk.data[6:])) + 1
+		height := int(readUint24(chunk.data[9:])) + 1
+		duration := int(readUint24(chunk.data[12:]))
+		flags := chunk.data[15]
+
+		frameData, err := buildWebPFrame(chunk.data[webpANMFHeaderSize:], width, height)
+		if err != nil {
+			return nil, nil, err
+		}
+		frameImage, err := webp.Decode(bytes.NewReader(frameData))
+		if err != nil {
+			log.Printf("Error decoding webp frame %d: %s\n", len(output), err)
+			return nil, nil, err
+		}
+
+		rect := image.Rect(x, y, x+width, y+height)
+		compositor.drawImage(frameImage, rect, flags&webpNoBlendFlag == 0)
+		output = append(output, compositor.snapshot())
+		// WebP durations are in milliseconds, GIF delays are in centiseconds
+		delays = append(delays, (duration+5)/10)
+
+		// The ANIM background colour is only a hint, browsers dispose to transparent so we do too
+		if flags&webpDisposeFlag != 0 {
+			compositor.clear(rect, color.Transparent)
+		}
+	}
+
+	if len(output) == 0 {
+		return nil, nil, errors.New("webp: no frames")
+	}
+
+	return output, delays, nil
+}
+
+// buildWebPFrame wraps the bitstream chunks of an ANMF frame into a standalone WebP file
+func buildWebPFrame(frameChunks []byte, width int, height int) ([]byte, error) {
+	chunks, err := readRIFFChunks(frameChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	content := new(bytes.Buffer)
+	for _, chunk := range chunks {
+		// Lossy frames keep their alpha in a separate ALPH chunk, which can only be used alongside VP8X
+		if chunk.fourCC == "ALPH" {
+			header := make([]byte, 10)
+			header[0] = webpAlphaFlag
+			putUint24(header[4:], uint32(width-1))
+			putUint24(header[7:], uint32(height-1))
+			writeRIFFChunk(content, "VP8X", header)
+		}
+		if chunk.fourCC == "ALPH" || chunk.fourCC == "VP8 " || chunk.fourCC == "VP8L" {
+			writeRIFFChunk(content, chunk.fourCC, chunk.data)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("RIFF")
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(content.Len()+4))
+	buf.Write(length[:])
+	buf.WriteString("WEBP")
+	_, _ = content.WriteTo(buf)
+	return buf.Bytes(), nil
+}
+
+func writeRIFFChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(data)))
+	buf.WriteString(fourCC)
+	buf.Write(length[:])
+	buf.Write(data)
+	if len(data)&1 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+func readUint24(data []byte) uint32 {
+	return uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+}
+
+func putUint24(data []byte, value uint32) {
+	data[0] = byte(value)
+	data[1] = byte(value >> 8)
+	data[2] = byte(value >> 16)
+}